@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Number of events returned per page if the caller doesn't specify a limit.
+const defaultAuditLimit = 50
+
+// Widths of the columns in the auth_event table.
+// Values from the request are cut to fit, otherwise a long User-Agent header would prevent the event from being recorded.
+const (
+	maxEventIdentity  = 255
+	maxEventIP        = 64
+	maxEventUserAgent = 512
+)
+
+// Records an event in the audit trail about the user making the request.
+// Failing to record an event is logged but does not fail the request.
+func recordEvent(c echo.Context, auditRepository *database.AuditRepository, eventType string, outcome string) {
+	event := newEvent(c, eventType, outcome)
+	event.Identity = logging.Identity(c)
+	if id, ok := logging.UserID(c); ok {
+		event.PersonID = &id
+	}
+	insertEvent(c, auditRepository, event)
+}
+
+// Records an event in the audit trail about another user than the one making the request,
+// such as a reset token that a recruiter created for an applicant.
+func recordUserEvent(c echo.Context, auditRepository *database.AuditRepository, user model.User, eventType string, outcome string) {
+	event := newEvent(c, eventType, outcome)
+	event.PersonID = &user.ID
+	event.Identity = user.Username
+	if event.Identity == "" {
+		event.Identity = user.Email
+	}
+	insertEvent(c, auditRepository, event)
+}
+
+// Returns an event with the device that made the request.
+func newEvent(c echo.Context, eventType string, outcome string) model.AuthEvent {
+	return model.AuthEvent{
+		Type:      eventType,
+		IP:        c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+		Outcome:   outcome,
+		CreatedAt: time.Now(),
+	}
+}
+
+func insertEvent(c echo.Context, auditRepository *database.AuditRepository, event model.AuthEvent) {
+	event.Identity = truncate(event.Identity, maxEventIdentity)
+	event.IP = truncate(event.IP, maxEventIP)
+	event.UserAgent = truncate(event.UserAgent, maxEventUserAgent)
+	if err := auditRepository.Insert(c.Request().Context(), event); err != nil {
+		logging.Logcf(logrus.ErrorLevel, c, "Failed to record %s event in audit trail: %v", event.Type, err)
+	}
+}
+
+// Returns the first n characters of s.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+type auditParams struct {
+	PersonID *int       `query:"person_id" validate:"omitempty,min=0"`
+	Identity string     `query:"identity"`
+	Type     string     `query:"type"      validate:"omitempty,oneof=login reset password_change email_change login_token_issued reset_token_issued verification_sent role_change account_disabled account_enabled"`
+	Outcome  string     `query:"outcome"`
+	From     *time.Time `query:"from"`
+	To       *time.Time `query:"to"`
+
+	Limit  uint64 `query:"limit"  validate:"omitempty,min=1,max=200"`
+	Offset uint64 `query:"offset"`
+}
+
+// Audit trail route handler.
+// Only recruiters are allowed to list events.
+func ListAuditEvents(c echo.Context, auditRepository *database.AuditRepository) error {
+	claims, err := requireRole(c, model.RoleRecruiter)
+	if err != nil {
+		return err
+	}
+
+	var params auditParams
+	// Check that all parameters are valid
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultAuditLimit
+	}
+
+	events, total, err := auditRepository.List(c.Request().Context(), database.AuditFilter{
+		PersonID: params.PersonID,
+		Identity: params.Identity,
+		Type:     params.Type,
+		Outcome:  params.Outcome,
+		From:     params.From,
+		To:       params.To,
+		Limit:    params.Limit,
+		Offset:   params.Offset,
+	})
+	if err != nil {
+		return err
+	}
+	logging.Logcf(logrus.InfoLevel, c, "Recruiter %d listed %d audit events", claims.User.ID, len(events))
+
+	return c.JSON(http.StatusOK, model.AuthEventPage{
+		Events: events,
+		Total:  total,
+		Limit:  int(params.Limit),
+		Offset: int(params.Offset),
+	})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/labstack/echo/v4"
+)
+
+// TokenCookieName is the name of the cookie that login tokens are sent in when cookie mode is enabled.
+const TokenCookieName = "login_token"
+
+// Sends login tokens to browsers in an HttpOnly cookie, so that scripts on the page can't read them.
+// A nil tokenCookie means that cookie mode is disabled.
+type tokenCookie struct {
+	sameSite http.SameSite
+}
+
+// Returns the token cookie configured in cfg, or nil if cookie mode is disabled.
+func newTokenCookie(cfg config.Auth) *tokenCookie {
+	if !cfg.TokenCookie {
+		return nil
+	}
+	sameSite := http.SameSiteStrictMode
+	switch cfg.TokenCookieSameSite {
+	case config.SameSiteLax:
+		sameSite = http.SameSiteLaxMode
+	case config.SameSiteNone:
+		sameSite = http.SameSiteNoneMode
+	}
+	return &tokenCookie{sameSite: sameSite}
+}
+
+func (t *tokenCookie) cookie(value string) *http.Cookie {
+	sameSite := http.SameSiteStrictMode
+	if t != nil {
+		sameSite = t.sameSite
+	}
+	// Browsers also accept Secure cookies over plain HTTP on localhost, which is enough for development
+	return &http.Cookie{
+		Name:     TokenCookieName,
+		Value:    value,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+}
+
+// Sets the token cookie to a login token. The cookie expires together with the token.
+// Does nothing if cookie mode is disabled.
+func (t *tokenCookie) set(c echo.Context, token string, expiry time.Time) {
+	if t == nil {
+		return
+	}
+	cookie := t.cookie(token)
+	cookie.Expires = expiry
+	c.SetCookie(cookie)
+}
+
+// Removes the token cookie from the browser if the request contains it.
+// This also works when cookie mode has been disabled since the cookie was set.
+func (t *tokenCookie) clear(c echo.Context) {
+	if !hasTokenCookie(c) {
+		return
+	}
+	cookie := t.cookie("")
+	cookie.MaxAge = -1
+	c.SetCookie(cookie)
+}
+
+// Returns true if the request contains the token cookie.
+func hasTokenCookie(c echo.Context) bool {
+	cookie, err := c.Cookie(TokenCookieName)
+	return err == nil && cookie.Value != ""
+}
+
+// Returns true if the browser sent its token in the cookie rather than the Authorization header.
+// Browsers send cookies with requests from other sites, so these requests must be protected against CSRF.
+func cookieAuthenticated(c echo.Context) bool {
+	return hasTokenCookie(c) && c.Request().Header.Get(echo.HeaderAuthorization) == ""
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// Returns a function that checks if an origin is in the allowlist.
+// Entries such as "https://*.example.com" match any subdomain of example.com, but not example.com itself.
+func originMatcher(allowOrigins []string) func(origin string) bool {
+	return func(origin string) bool {
+		for _, allowed := range allowOrigins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+
+			scheme, suffix, ok := strings.Cut(allowed, "://*.")
+			if !ok {
+				continue
+			}
+			prefix := scheme + "://"
+			if len(origin) > len(prefix) && strings.EqualFold(origin[:len(prefix)], prefix) {
+				host := origin[len(prefix):]
+				if len(host) > len(suffix)+1 && strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(suffix)) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// Returns true if the origin is the host the request was sent to, in which case the request is not cross-origin.
+func sameOrigin(c echo.Context, origin string) bool {
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, c.Request().Host)
+}
+
+// CORSMiddleware only allows browsers to call the API from the origins in the allowlist.
+// Cross-origin requests from other origins are logged.
+func CORSMiddleware(cfg config.CORS) echo.MiddlewareFunc {
+	allowed := originMatcher(cfg.AllowOrigins)
+	cors := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc: func(origin string) (bool, error) {
+			return allowed(origin), nil
+		},
+		AllowMethods:     cfg.AllowMethods,
+		AllowHeaders:     cfg.AllowHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		handler := cors(next)
+		return func(c echo.Context) error {
+			origin := c.Request().Header.Get(echo.HeaderOrigin)
+			if origin != "" && !allowed(origin) && !sameOrigin(c, origin) {
+				logging.Logcf(logrus.WarnLevel, c, "CORS request from disallowed origin %s", origin)
+			}
+			return handler(c)
+		}
+	}
+}
@@ -3,10 +3,13 @@ package api
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/IV1201-Group-2/login-service/database"
 	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/metrics"
 	"github.com/IV1201-Group-2/login-service/service"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
@@ -23,6 +26,8 @@ type Error struct {
 	Details any `json:"details,omitempty"`
 	// Internal wrapped error. Not visible to users.
 	Internal error `json:"-"`
+	// Message in the user's language. Set by ErrorHandler.
+	Message string `json:"message,omitempty"`
 }
 
 // Describes the API error.
@@ -36,12 +41,12 @@ func (e *Error) Error() string {
 // Attaches detailed user-visible information to an API error.
 // This is intended to give the API consumer more information about where and how it occurred.
 func (e *Error) WithDetails(details any) *Error {
-	return &Error{StatusCode: e.StatusCode, ErrorType: e.ErrorType, Details: details, Internal: e.Internal}
+	return &Error{StatusCode: e.StatusCode, ErrorType: e.ErrorType, Details: details, Internal: e.Internal, Message: e.Message}
 }
 
 // Attaches an internal error to an API error.
 func (e *Error) Wrap(err error) *Error {
-	return &Error{StatusCode: e.StatusCode, ErrorType: e.ErrorType, Details: e.Details, Internal: err}
+	return &Error{StatusCode: e.StatusCode, ErrorType: e.ErrorType, Details: e.Details, Internal: err, Message: e.Message}
 }
 
 // If an error has been wrapped in a.Internal, return the error.
@@ -60,29 +65,76 @@ func (e *Error) Is(target error) bool {
 
 var (
 	// ErrUnknown represents an unknown error.
-	ErrUnknown = &Error{http.StatusInternalServerError, "UNKNOWN", nil, nil}
+	ErrUnknown = &Error{http.StatusInternalServerError, "UNKNOWN", nil, nil, ""}
 	// ErrServiceUnavailable indicates that an external service such as the database is unavailable.
-	ErrServiceUnavailable = &Error{http.StatusInternalServerError, "SERVICE_UNAVAILABLE", nil, nil}
+	ErrServiceUnavailable = &Error{http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", nil, nil, ""}
 
 	// ErrMissingParameters indicates that the user did not provide identity, password or desired role.
-	ErrMissingParameters = &Error{http.StatusBadRequest, "MISSING_PARAMETERS", nil, nil}
+	// The details list each parameter that is missing or invalid.
+	ErrMissingParameters = &Error{http.StatusBadRequest, "MISSING_PARAMETERS", nil, nil, ""}
+	// ErrMalformedRequest indicates that the request body could not be parsed or has an unsupported content type.
+	ErrMalformedRequest = &Error{http.StatusBadRequest, "MALFORMED_REQUEST", nil, nil, ""}
 	// ErrMissingParameters indicates that the user does not have a password in the database.
-	ErrMissingPassword = &Error{http.StatusNotFound, "MISSING_PASSWORD", nil, nil}
+	ErrMissingPassword = &Error{http.StatusNotFound, "MISSING_PASSWORD", nil, nil, ""}
+	// ErrMissingEmail indicates that the user does not have an e-mail address that a link can be mailed to.
+	ErrMissingEmail = &Error{http.StatusNotFound, "MISSING_EMAIL", nil, nil, ""}
 
 	// ErrWrongIdentity indicates that no account was found with the provided parameters.
-	ErrWrongIdentity = &Error{http.StatusUnauthorized, "WRONG_IDENTITY", nil, nil}
+	ErrWrongIdentity = &Error{http.StatusUnauthorized, "WRONG_IDENTITY", nil, nil, ""}
+
+	// ErrAccountDisabled indicates that the account has been disabled by a recruiter.
+	ErrAccountDisabled = &Error{http.StatusForbidden, "ACCOUNT_DISABLED", nil, nil, ""}
+	// ErrAccountLocked indicates that too many wrong passwords were entered for the account recently.
+	ErrAccountLocked = &Error{http.StatusTooManyRequests, "ACCOUNT_LOCKED", nil, nil, ""}
+
+	// ErrEmailNotVerified indicates that the user has not opened the link that verifies their e-mail address.
+	ErrEmailNotVerified = &Error{http.StatusForbidden, "EMAIL_NOT_VERIFIED", nil, nil, ""}
+	// ErrIdentityTaken indicates that another user already has the e-mail address or personal number.
+	// The details list each parameter that is taken.
+	ErrIdentityTaken = &Error{http.StatusConflict, "IDENTITY_TAKEN", nil, nil, ""}
 
 	// ErrAlreadyLoggedIn indicates that the user is already logged in (JWT token was provided).
-	ErrAlreadyLoggedIn = &Error{http.StatusBadRequest, "ALREADY_LOGGED_IN", nil, nil}
+	ErrAlreadyLoggedIn = &Error{http.StatusBadRequest, "ALREADY_LOGGED_IN", nil, nil, ""}
 	// ErrTokenNotProvided indicates that the user did not provide a token for reset API.
-	ErrTokenNotProvided = &Error{http.StatusUnauthorized, "TOKEN_NOT_PROVIDED", nil, nil} // #nosec G101
+	ErrTokenNotProvided = &Error{http.StatusUnauthorized, "TOKEN_NOT_PROVIDED", nil, nil, ""} // #nosec G101
 	// ErrTokenInvalid indicates that the user provided an invalid or expired token.
-	ErrTokenInvalid = &Error{http.StatusUnauthorized, "INVALID_TOKEN", nil, nil}
+	ErrTokenInvalid = &Error{http.StatusUnauthorized, "INVALID_TOKEN", nil, nil, ""}
+
+	// ErrUserNotFound indicates that a recruiter tried to manage a user that does not exist.
+	ErrUserNotFound = &Error{http.StatusNotFound, "USER_NOT_FOUND", nil, nil, ""}
+	// ErrSessionNotFound indicates that the user tried to revoke a session that does not exist or has already ended.
+	ErrSessionNotFound = &Error{http.StatusNotFound, "SESSION_NOT_FOUND", nil, nil, ""}
+
+	// ErrForbidden indicates that the user is logged in but does not have the role required by the route.
+	ErrForbidden = &Error{http.StatusForbidden, "FORBIDDEN", nil, nil, ""}
+	// ErrCSRFFailed indicates that a form was submitted from another site without a valid CSRF token.
+	ErrCSRFFailed = &Error{http.StatusForbidden, "CSRF_FAILED", nil, nil, ""}
 
+	// ErrFeatureDisabled indicates that the route depends on a feature that is not enabled, such as registration or mail.
+	ErrFeatureDisabled = &Error{http.StatusNotFound, "FEATURE_DISABLED", nil, nil, ""}
 	// ErrInvalidRoute indicates that the user tried to access an invalid route.
-	ErrInvalidRoute = &Error{http.StatusNotFound, "INVALID_ROUTE", nil, nil}
+	ErrInvalidRoute = &Error{http.StatusNotFound, "INVALID_ROUTE", nil, nil, ""}
+	// ErrHTTPSRequired indicates that a request other than GET or HEAD was sent over plain HTTP.
+	ErrHTTPSRequired = &Error{http.StatusBadRequest, "HTTPS_REQUIRED", nil, nil, ""}
 )
 
+// Returns the outcome of a handler for metrics: either success or the error type shown to the user.
+func outcome(err error) string {
+	var apiErr *Error
+	var databaseErr *database.Error
+
+	switch {
+	case err == nil:
+		return metrics.OutcomeSuccess
+	case errors.As(err, &apiErr):
+		return apiErr.ErrorType
+	case errors.As(err, &databaseErr):
+		return ErrServiceUnavailable.ErrorType
+	default:
+		return ErrUnknown.ErrorType
+	}
+}
+
 // Rewrites errors returned by Echo to follow shared API rules.
 func rewriteEchoErrors(err *echo.HTTPError, c echo.Context) *Error {
 	result := ErrUnknown.Wrap(err)
@@ -119,11 +171,29 @@ func ErrorHandler(e error, c echo.Context) {
 	case errors.As(e, &databaseErr):
 		logging.Logcf(logrus.ErrorLevel, c, "Error occurred in database layer: %v", e)
 		userVisibleErr = ErrServiceUnavailable.Wrap(e)
+		// Tell the client when the circuit breaker will allow requests again
+		if after, ok := database.RetryAfter(e); ok {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(after.Seconds()))))
+		}
 	default:
 		logging.Logcf(logrus.ErrorLevel, c, "Recovered from unexpected error: %v", e)
 	}
 
-	if err := c.JSON(userVisibleErr.StatusCode, userVisibleErr); err != nil {
+	message, lang := LocalizedMessage(userVisibleErr, c.Request().Header.Get("Accept-Language"))
+	c.Response().Header().Set("Content-Language", lang)
+	c.Response().Header().Add(echo.HeaderVary, "Accept-Language")
+
+	var err error
+	if wantsProblem(c) {
+		err = writeProblem(c, userVisibleErr, message)
+	} else {
+		// The error format depends on the Accept header
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+		localized := *userVisibleErr
+		localized.Message = message.Message
+		err = c.JSON(localized.StatusCode, &localized)
+	}
+	if err != nil {
 		logging.Logcf(logrus.ErrorLevel, c, "Error occurred in HTTP error handler: %v", err)
 	}
 }
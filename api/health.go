@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/model"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+// Readiness checks give up on the database after this long.
+const readinessPingTimeout = 2 * time.Second
+
+// Runs a single readiness check and measures how long it took.
+func runCheck(check func() error) model.HealthCheck {
+	start := time.Now()
+	err := check()
+	result := model.HealthCheck{
+		Status:    model.HealthStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = model.HealthStatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness route handler.
+// The service is alive as long as it can respond to requests.
+func Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, model.HealthResponse{Status: model.HealthStatusOK})
+}
+
+// Readiness route handler.
+// The service is ready when the database is reachable and the signing key is loaded.
+func Readiness(c echo.Context, db *sql.DB, auth *echojwt.Config) error {
+	checks := map[string]model.HealthCheck{
+		"database": runCheck(func() error {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), readinessPingTimeout)
+			defer cancel()
+			return db.PingContext(ctx)
+		}),
+		"signing_key": runCheck(func() error {
+			if auth == nil || auth.SigningKey == nil {
+				return ErrNoSecret
+			}
+			return nil
+		}),
+	}
+
+	res := model.HealthResponse{Status: model.HealthStatusOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != model.HealthStatusOK {
+			res.Status = model.HealthStatusUnavailable
+			return c.JSON(http.StatusServiceUnavailable, res)
+		}
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -2,19 +2,39 @@ package api
 
 import (
 	"errors"
-	"os"
 
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/logging"
 	"github.com/IV1201-Group-2/login-service/model"
 	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
-func errorHandlerFunc(_ echo.Context, err error) error {
+func errorHandlerFunc(c echo.Context, err error) error {
 	// Allow requests without a token set
 	if errors.Is(err, echojwt.ErrJWTMissing) {
 		return nil
 	}
+	if errors.Is(err, database.ErrUserDisabled) {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user of token has been disabled")
+		return ErrAccountDisabled.Wrap(err)
+	}
+	if errors.Is(err, database.ErrUserNotFound) {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user of token no longer exists")
+		return ErrTokenInvalid.Wrap(err)
+	}
+	if errors.Is(err, database.ErrSessionNotFound) {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: session of token has been revoked or has expired")
+		return ErrTokenInvalid.Wrap(err)
+	}
+	// The session of the token couldn't be checked
+	var databaseErr *database.Error
+	if errors.As(err, &databaseErr) {
+		return databaseErr
+	}
 	if errors.Is(err, echojwt.ErrJWTInvalid) {
 		return ErrTokenInvalid.Wrap(err)
 	}
@@ -26,21 +46,116 @@ func newClaimsFunc(_ echo.Context) jwt.Claims {
 	return &model.UserClaims{}
 }
 
+// Returns a function that parses tokens signed with signingKey.
+// Tokens are rejected once the account has been disabled or deleted,
+// and tokens that belong to a session are only accepted while the session is active.
+// The role in the token is replaced with the current role of the user, so that changing the role takes effect immediately.
+func newParseTokenFunc(signingKey []byte, userRepository *database.UserRepository, sessionRepository *database.SessionRepository) func(echo.Context, string) (any, error) {
+	return func(c echo.Context, auth string) (any, error) {
+		token, err := jwt.ParseWithClaims(auth, newClaimsFunc(c), func(_ *jwt.Token) (any, error) {
+			return signingKey, nil
+		}, jwt.WithValidMethods([]string{echojwt.AlgorithmHS256}))
+		if err != nil {
+			return nil, err
+		}
+
+		claims, ok := token.Claims.(*model.UserClaims)
+		if !ok {
+			return token, nil
+		}
+		ctx := c.Request().Context()
+		role, disabled, err := userRepository.Access(ctx, claims.User.ID)
+		if err != nil {
+			return nil, err
+		}
+		if disabled {
+			return nil, database.ErrUserDisabled
+		}
+		claims.User.Role = role
+		// Reset tokens and tokens signed before sessions were tracked don't belong to a session
+		if claims.SessionID != "" {
+			if err := sessionRepository.Touch(ctx, claims.User.ID, claims.SessionID); err != nil {
+				return nil, err
+			}
+		}
+		return token, nil
+	}
+}
+
+// Returns an error handler that also removes the token cookie if it contains a token that is no longer accepted.
+// Otherwise the browser would keep sending it, and the user couldn't log in again.
+func newErrorHandlerFunc(cookie *tokenCookie) func(echo.Context, error) error {
+	return func(c echo.Context, err error) error {
+		err = errorHandlerFunc(c, err)
+		if errors.Is(err, ErrTokenInvalid) && cookieAuthenticated(c) {
+			cookie.clear(c)
+		}
+		return err
+	}
+}
+
 var authConfigTemplate = echojwt.Config{
-	ErrorHandler:           errorHandlerFunc,
 	ContinueOnIgnoredError: true,
 	NewClaimsFunc:          newClaimsFunc,
 }
 
-// ErrNoSecret indicates that the JWT_SECRET environment variable is not set.
+// ErrNoSecret indicates that the JWT secret is not set.
 var ErrNoSecret = errors.New("$JWT_SECRET must be set")
 
-// NewAuthConfig creates a new echojwt config from JWT_SECRET.
-func NewAuthConfig() (*echojwt.Config, error) {
-	if secret, ok := os.LookupEnv("JWT_SECRET"); ok {
-		config := authConfigTemplate
-		config.SigningKey = []byte(secret)
-		return &config, nil
+// NewAuthConfig creates a new echojwt config that signs tokens with the JWT secret in cfg.
+// Tokens are read from the Authorization header, or from the token cookie if cookie mode is enabled.
+// Tokens are rejected once their user has been disabled in userRepository or their session has been revoked in sessionRepository.
+func NewAuthConfig(cfg config.Auth, userRepository *database.UserRepository, sessionRepository *database.SessionRepository) (*echojwt.Config, error) {
+	if cfg.JWTSecret == "" {
+		return nil, ErrNoSecret
+	}
+	signingKey := []byte(cfg.JWTSecret)
+	authConfig := authConfigTemplate
+	authConfig.SigningKey = signingKey
+	authConfig.ParseTokenFunc = newParseTokenFunc(signingKey, userRepository, sessionRepository)
+	authConfig.ErrorHandler = newErrorHandlerFunc(newTokenCookie(cfg))
+	if cfg.TokenCookie {
+		// The header is checked first, so API clients aren't affected by a cookie left in the browser
+		authConfig.TokenLookup = "header:" + echo.HeaderAuthorization + ":Bearer ,cookie:" + TokenCookieName
+	}
+	return &authConfig, nil
+}
+
+// Returns the claims of the token provided by the user, if any.
+func userClaims(c echo.Context) (*model.UserClaims, bool) {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := token.Claims.(*model.UserClaims)
+	return claims, ok
+}
+
+// Checks that the user is logged in.
+// Reset tokens are not accepted since they only grant access to the reset API.
+func requireLogin(c echo.Context) (*model.UserClaims, error) {
+	claims, ok := userClaims(c)
+	if !ok {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user has no login token")
+		return nil, ErrTokenNotProvided
+	}
+	if claims.Usage != model.TokenUsageLogin {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user provided a %s token", claims.Usage)
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// Checks that the user is logged in with the specified role.
+// The role has been read from the database by the jwt middleware, so demoted users lose access immediately.
+func requireRole(c echo.Context, role model.Role) (*model.UserClaims, error) {
+	claims, err := requireLogin(c)
+	if err != nil {
+		return nil, err
+	}
+	if claims.User.Role != role {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user %d does not have role %d", claims.User.ID, role)
+		return nil, ErrForbidden
 	}
-	return nil, ErrNoSecret
+	return claims, nil
 }
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Locks accounts after too many wrong passwords, whether they were entered at login,
+// when changing the password or when changing the e-mail address.
+// Wrong passwords are counted for each IP address, so that guessing from one address doesn't lock out the user everywhere.
+// They are counted from the audit trail, so every instance of the service sees the same count.
+// A nil lockout means that lockout is disabled.
+type lockout struct {
+	maxAttempts int
+	period      time.Duration
+}
+
+// Returns the lockout configured in cfg, or nil if lockout is disabled.
+func newLockout(cfg config.Auth) *lockout {
+	if cfg.MaxFailedAttempts == 0 {
+		return nil
+	}
+	return &lockout{maxAttempts: cfg.MaxFailedAttempts, period: cfg.LockoutPeriod}
+}
+
+// Returns when the accounts of the users that are locked for ip are unlocked. Users that aren't locked are left out.
+// If ip is empty, accounts that are locked for any address are returned.
+// An account stays locked until the oldest of the counted wrong passwords is older than the lockout period.
+func (l *lockout) lockedUntil(c echo.Context, auditRepository *database.AuditRepository, ip string, personIDs ...int) (map[int]time.Time, error) {
+	if l == nil {
+		return map[int]time.Time{}, nil
+	}
+	failures, err := auditRepository.NthNewest(c.Request().Context(), personIDs, ip, ErrWrongIdentity.ErrorType, time.Now().Add(-l.period), l.maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	for id, failure := range failures {
+		failures[id] = failure.Add(l.period)
+	}
+	return failures, nil
+}
+
+// Returns ErrAccountLocked if too many wrong passwords have been entered for the user from the address of the request.
+// Locked accounts get this error whether or not the password was correct, so guessing can't continue.
+func (l *lockout) check(c echo.Context, auditRepository *database.AuditRepository, personID int) error {
+	locked, err := l.lockedUntil(c, auditRepository, c.RealIP(), personID)
+	if err != nil {
+		return err
+	}
+	until, ok := locked[personID]
+	if !ok {
+		return nil
+	}
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(until).Seconds()))))
+	logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user %d is locked until %s", personID, until.Format(logging.TimestampFormat))
+	return ErrAccountLocked
+}
+
+// Returns ErrAccountLocked if the user with the identity is locked, like check.
+// It is called before the password is compared, so that guesses at a locked account don't cost a bcrypt comparison.
+// Identities that don't belong to a user are left for authentication to reject.
+func (l *lockout) checkIdentity(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, identity string) error {
+	if l == nil {
+		return nil
+	}
+	identity = strings.TrimSpace(identity)
+	if identity == "" {
+		return nil
+	}
+	user, _ := userRepository.Query(c.Request().Context(), identity)
+	if user == nil {
+		return nil
+	}
+	if err := l.check(c, auditRepository, user.ID); err != nil {
+		logging.SetUserID(c, user.ID)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Message catalogs are named after the language they contain, such as sv.json.
+//
+//go:embed messages/*.json
+var messageFiles embed.FS
+
+// Messages are shown in English if the user doesn't accept any other language.
+var defaultLanguage = language.English
+
+// Message describes an error type to users in one language.
+type Message struct {
+	// Short summary of the error type.
+	Title string `json:"title"`
+	// Full sentence that can be shown to the user.
+	Message string `json:"message"`
+}
+
+var (
+	// Messages keyed by language and error type.
+	catalogs = map[language.Tag]map[string]Message{}
+	// Languages that have a catalog, the default language first.
+	languages       []language.Tag
+	languageMatcher language.Matcher
+)
+
+func init() {
+	if err := loadCatalogs(messageFiles); err != nil {
+		panic(err)
+	}
+}
+
+// Loads every message catalog in fsys.
+func loadCatalogs(fsys fs.FS) error {
+	paths, err := fs.Glob(fsys, "messages/*.json")
+	if err != nil {
+		return err
+	}
+
+	languages = []language.Tag{defaultLanguage}
+	for _, file := range paths {
+		tag, err := language.Parse(strings.TrimSuffix(path.Base(file), ".json"))
+		if err != nil {
+			return fmt.Errorf("message catalog %s: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		catalog := map[string]Message{}
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("message catalog %s: %w", file, err)
+		}
+
+		catalogs[tag] = catalog
+		if tag != defaultLanguage {
+			languages = append(languages, tag)
+		}
+	}
+	languageMatcher = language.NewMatcher(languages)
+	return nil
+}
+
+// LocalizedMessage returns the message for an error in the language that best matches an Accept-Language header.
+// If the language has no message for the error, the message in the default language is returned.
+// The language of the message is also returned.
+func LocalizedMessage(err *Error, acceptLanguage string) (Message, string) {
+	// Invalid headers match the default language
+	accepted, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, index, _ := languageMatcher.Match(accepted...)
+	tag := languages[index]
+
+	if message, ok := catalogs[tag][err.ErrorType]; ok {
+		return message, tag.String()
+	}
+	if message, ok := catalogs[defaultLanguage][err.ErrorType]; ok {
+		return message, defaultLanguage.String()
+	}
+	return Message{Title: http.StatusText(err.StatusCode)}, defaultLanguage.String()
+}
@@ -0,0 +1,545 @@
+package api
+
+import (
+	"go/token"
+	"maps"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/labstack/echo/v4"
+)
+
+// Path of the route that serves the OpenAPI document.
+const openAPIPath = "/api/openapi.json"
+
+// Describes a route in the OpenAPI document.
+type operation struct {
+	method string
+	// Path relative to the version prefix if the route is versioned
+	path      string
+	versioned bool
+	summary   string
+	// The route requires a bearer token
+	authenticated bool
+	// Path parameters, and the request body for POST routes or query parameters for GET routes.
+	// Nil if the route takes no parameters.
+	params any
+	// Responses returned on success keyed by status code, nil values are plain text or no content for 204
+	responses map[int]any
+	// API errors that the route can return
+	errors []*Error
+}
+
+// Errors that can be returned by any route that accesses the database.
+var databaseErrors = []*Error{ErrUnknown, ErrServiceUnavailable}
+
+// Every route registered by NewServer must be described here.
+var operations = []operation{
+	{
+		method:    http.MethodPost,
+		path:      "/login",
+		versioned: true,
+		summary:   "Log in with a username or e-mail address and receive a login token",
+		params:    loginParams{},
+		responses: map[int]any{http.StatusOK: model.LoginTokenResponse{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrMissingPassword, ErrWrongIdentity, ErrAlreadyLoggedIn, ErrTokenInvalid, ErrAccountDisabled, ErrAccountLocked, ErrEmailNotVerified, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/reset",
+		versioned:     true,
+		summary:       "Set a new password with a reset token and receive a login token",
+		authenticated: true,
+		params:        resetParams{},
+		responses:     map[int]any{http.StatusOK: model.LoginTokenResponse{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:    http.MethodPost,
+		path:      "/register",
+		versioned: true,
+		summary:   "Register as an applicant and receive a link that verifies the e-mail address",
+		params:    registerParams{},
+		responses: map[int]any{http.StatusAccepted: model.RegistrationResponse{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrAlreadyLoggedIn, ErrTokenInvalid, ErrAccountDisabled, ErrFeatureDisabled, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:    http.MethodGet,
+		path:      "/verify",
+		versioned: true,
+		summary:   "Verify an e-mail address or confirm an e-mail change with the token from a mailed link",
+		params:    verifyParams{},
+		responses: map[int]any{http.StatusOK: model.User{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrIdentityTaken, ErrTokenInvalid, ErrAccountDisabled,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/password",
+		versioned:     true,
+		summary:       "Change the password of the logged in user and receive a new login token",
+		authenticated: true,
+		params:        passwordParams{},
+		responses:     map[int]any{http.StatusOK: model.LoginTokenResponse{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrWrongIdentity, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrAccountLocked, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/email",
+		versioned:     true,
+		summary:       "Mail a link that changes the e-mail address of the logged in user to a new address",
+		authenticated: true,
+		params:        emailParams{},
+		responses:     map[int]any{http.StatusAccepted: model.EmailChangeResponse{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrWrongIdentity, ErrIdentityTaken, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrAccountLocked, ErrFeatureDisabled, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/logout",
+		versioned:     true,
+		summary:       "Log out of the session of the login token, if any, and remove the token cookie",
+		authenticated: true,
+		responses:     map[int]any{http.StatusNoContent: nil},
+		errors: append([]*Error{
+			ErrTokenInvalid, ErrAccountDisabled, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/sessions",
+		versioned:     true,
+		summary:       "List the devices that the logged in user is logged in on",
+		authenticated: true,
+		responses:     map[int]any{http.StatusOK: model.SessionList{}},
+		errors: append([]*Error{
+			ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/sessions/{id}/revoke",
+		versioned:     true,
+		summary:       "Log the logged in user out of one of their sessions and list the remaining sessions",
+		authenticated: true,
+		params:        sessionParams{},
+		responses:     map[int]any{http.StatusOK: model.SessionList{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrSessionNotFound, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:    http.MethodGet,
+		path:      csrfPath,
+		summary:   "Get a CSRF token for form submissions and set the matching cookie",
+		responses: map[int]any{http.StatusOK: model.CSRFTokenResponse{}},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/audit",
+		versioned:     true,
+		summary:       "List events in the audit trail (recruiters only)",
+		authenticated: true,
+		params:        auditParams{},
+		responses:     map[int]any{http.StatusOK: model.AuthEventPage{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrForbidden,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/users",
+		versioned:     true,
+		summary:       "List and search users (recruiters only)",
+		authenticated: true,
+		params:        userListParams{},
+		responses:     map[int]any{http.StatusOK: model.UserPage{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrForbidden,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/users/{id}",
+		versioned:     true,
+		summary:       "Get the status of a user (recruiters only)",
+		authenticated: true,
+		params:        userParams{},
+		responses:     map[int]any{http.StatusOK: model.UserStatus{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrForbidden, ErrUserNotFound,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/{id}/reset",
+		versioned:     true,
+		summary:       "Log a user out everywhere and mail them a link that lets them choose a new password (recruiters only)",
+		authenticated: true,
+		params:        userParams{},
+		responses:     map[int]any{http.StatusNoContent: nil},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrForbidden, ErrUserNotFound, ErrMissingEmail, ErrFeatureDisabled, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/{id}/role",
+		versioned:     true,
+		summary:       "Change the role of another user (recruiters only)",
+		authenticated: true,
+		params:        roleParams{},
+		responses:     map[int]any{http.StatusOK: model.UserStatus{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrForbidden, ErrUserNotFound, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/{id}/disable",
+		versioned:     true,
+		summary:       "Disable the account of another user and revoke their tokens (recruiters only)",
+		authenticated: true,
+		params:        userParams{},
+		responses:     map[int]any{http.StatusOK: model.UserStatus{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrForbidden, ErrUserNotFound, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/{id}/enable",
+		versioned:     true,
+		summary:       "Enable the account of another user (recruiters only)",
+		authenticated: true,
+		params:        userParams{},
+		responses:     map[int]any{http.StatusOK: model.UserStatus{}},
+		errors: append([]*Error{
+			ErrMissingParameters, ErrMalformedRequest, ErrTokenNotProvided, ErrTokenInvalid, ErrAccountDisabled, ErrForbidden, ErrUserNotFound, ErrCSRFFailed,
+		}, databaseErrors...),
+	},
+	{
+		method:    http.MethodGet,
+		path:      openAPIPath,
+		summary:   "Get this OpenAPI document",
+		responses: map[int]any{http.StatusOK: map[string]any{}},
+	},
+	{
+		method:    http.MethodGet,
+		path:      "/healthz",
+		summary:   "Check that the service is alive",
+		responses: map[int]any{http.StatusOK: model.HealthResponse{}},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/readyz",
+		summary: "Check that the service is ready to handle requests",
+		responses: map[int]any{
+			http.StatusOK:                 model.HealthResponse{},
+			http.StatusServiceUnavailable: model.HealthResponse{},
+		},
+	},
+}
+
+// Builds JSON schemas from Go types.
+// Exported named structs are added to components and referenced.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Types that can't be described by their Go type alone.
+var namedSchemas = map[reflect.Type]map[string]any{
+	reflect.TypeOf(model.Role(0)): {
+		"type":        "integer",
+		"enum":        []int{int(model.RoleRecruiter), int(model.RoleApplicant)},
+		"description": "1 is a recruiter, 2 is an applicant",
+	},
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if schema, ok := namedSchemas[t]; ok {
+		return maps.Clone(schema)
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if !token.IsExported(t.Name()) {
+			return b.object(t, "json")
+		}
+		if _, ok := b.components[t.Name()]; !ok {
+			// Reserve the name first in case the type refers to itself
+			b.components[t.Name()] = nil
+			b.components[t.Name()] = b.object(t, "json")
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		// Interfaces can contain any value
+		return map[string]any{}
+	}
+}
+
+// A struct field as it appears in JSON or a query string.
+type property struct {
+	name     string
+	schema   map[string]any
+	required bool
+}
+
+// Returns the fields of a struct as they are named by tag. Embedded structs are flattened.
+func (b *schemaBuilder) properties(t reflect.Type, tag string) []property {
+	var props []property
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			props = append(props, b.properties(field.Type, tag)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		schema := b.schema(field.Type)
+		required := tag == "json" && !strings.Contains(opts, "omitempty")
+		if validate, ok := field.Tag.Lookup("validate"); ok {
+			// Request parameters are required if they are validated as required
+			required = applyValidation(schema, validate)
+		}
+		props = append(props, property{name: name, schema: schema, required: required})
+	}
+	return props
+}
+
+func (b *schemaBuilder) object(t reflect.Type, tag string) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for _, prop := range b.properties(t, tag) {
+		properties[prop.name] = prop.schema
+		if prop.required {
+			required = append(required, prop.name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Adds the constraints of a validate tag to a schema. Returns true if the field is required.
+func applyValidation(schema map[string]any, validate string) bool {
+	required := false
+	for _, rule := range strings.Split(validate, ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max":
+			keywords := map[string]string{"min": "minimum", "max": "maximum"}
+			// Strings are validated by length
+			if schema["type"] == "string" {
+				keywords = map[string]string{"min": "minLength", "max": "maxLength"}
+			}
+			if n, err := strconv.Atoi(value); err == nil {
+				schema[keywords[name]] = n
+			}
+		case "email":
+			schema["format"] = "email"
+		case personalNumberRule:
+			schema["pattern"] = `^\d{8}[-+]?\d{4}$`
+		case "oneof":
+			values := []any{}
+			for _, value := range strings.Fields(value) {
+				if n, err := strconv.Atoi(value); err == nil && schema["type"] == "integer" {
+					values = append(values, n)
+				} else {
+					values = append(values, value)
+				}
+			}
+			schema["enum"] = values
+		}
+	}
+	return required
+}
+
+// Describes the errors returned by an operation, grouped by status code.
+// Version 2 routes only return problem details, other routes return them if the client accepts them.
+func (b *schemaBuilder) errorResponses(errs []*Error, problemOnly bool) map[int]any {
+	byStatus := map[int][]string{}
+	for _, err := range errs {
+		byStatus[err.StatusCode] = append(byStatus[err.StatusCode], err.ErrorType)
+	}
+
+	responses := map[int]any{}
+	for status, types := range byStatus {
+		sort.Strings(types)
+		// The error type is restricted to the errors that the operation returns
+		schema := func(errorFormat any) map[string]any {
+			return map[string]any{
+				"schema": map[string]any{
+					"allOf": []any{
+						b.schema(reflect.TypeOf(errorFormat)),
+						map[string]any{"properties": map[string]any{"error": map[string]any{"enum": types}}},
+					},
+				},
+			}
+		}
+
+		content := map[string]any{MIMEApplicationProblemJSON: schema(Problem{})}
+		if !problemOnly {
+			content[echo.MIMEApplicationJSON] = schema(Error{})
+		}
+		responses[status] = map[string]any{"description": strings.Join(types, ", "), "content": content}
+	}
+	return responses
+}
+
+func (b *schemaBuilder) operation(op operation, problemOnly bool) map[string]any {
+	responses := map[string]any{}
+	for status, errResponse := range b.errorResponses(op.errors, problemOnly) {
+		responses[strconv.Itoa(status)] = errResponse
+	}
+	for status, body := range op.responses {
+		response := map[string]any{"description": http.StatusText(status)}
+		switch {
+		case body != nil:
+			response["content"] = map[string]any{echo.MIMEApplicationJSON: map[string]any{"schema": b.schema(reflect.TypeOf(body))}}
+		case status != http.StatusNoContent:
+			response["content"] = map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+
+	result := map[string]any{"summary": op.summary, "responses": responses}
+	if op.authenticated {
+		// Browsers send the token in a cookie instead if cookie mode is enabled
+		result["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"cookieAuth": []string{}}}
+	}
+	if op.params == nil {
+		return result
+	}
+
+	paramsType := reflect.TypeOf(op.params)
+	parameters := []any{}
+	for _, prop := range b.properties(paramsType, "param") {
+		parameters = append(parameters, map[string]any{
+			"name":     prop.name,
+			"in":       "path",
+			"required": true,
+			"schema":   prop.schema,
+		})
+	}
+	if op.method == http.MethodGet {
+		for _, prop := range b.properties(paramsType, "query") {
+			parameters = append(parameters, map[string]any{
+				"name":     prop.name,
+				"in":       "query",
+				"required": prop.required,
+				"schema":   prop.schema,
+			})
+		}
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	// Parameters can be sent as JSON or as a form, with the same names
+	if schema := b.object(paramsType, "json"); op.method != http.MethodGet && len(schema["properties"].(map[string]any)) > 0 {
+		result["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				echo.MIMEApplicationJSON: map[string]any{"schema": schema},
+				echo.MIMEApplicationForm: map[string]any{"schema": schema},
+			},
+		}
+	}
+	return result
+}
+
+// Builds the OpenAPI document from the operations table.
+func buildOpenAPI() map[string]any {
+	b := &schemaBuilder{components: map[string]any{}}
+
+	paths := map[string]map[string]any{}
+	add := func(path string, op operation, problemOnly bool) {
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(op.method)] = b.operation(op, problemOnly)
+	}
+	for _, op := range operations {
+		if op.versioned {
+			add(apiV1Prefix+op.path, op, false)
+			add(apiV2Prefix+op.path, op, true)
+		} else {
+			add(op.path, op, false)
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Login Service",
+			"description": "Authenticates users of the recruitment application and issues JWT tokens.",
+			"version":     "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": TokenCookieName},
+			},
+		},
+	}
+}
+
+// The document only depends on types, so it is built once.
+var openAPIDocument = sync.OnceValue(buildOpenAPI)
+
+// OpenAPI route handler.
+func OpenAPI(c echo.Context) error {
+	return c.JSON(http.StatusOK, openAPIDocument())
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationProblemJSON is the content type of RFC 7807 problem details.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem types are identified by a link to their documentation.
+const problemTypeBase = "https://github.com/IV1201-Group-2/docs/blob/main/apis/errors.md#"
+
+// Problem describes an API error as RFC 7807 problem details.
+type Problem struct {
+	// URI that identifies the problem type and links to its documentation.
+	Type string `json:"type"`
+	// Short summary of the problem type.
+	Title string `json:"title"`
+	// HTTP status code.
+	Status int `json:"status"`
+	// Explanation of this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Path of the request that caused the problem.
+	Instance string `json:"instance,omitempty"`
+
+	// Error type, the same as in version 1 errors.
+	ErrorType string `json:"error"`
+	// Error details, the same as in version 1 errors.
+	Details any `json:"details,omitempty"`
+}
+
+// Converts an API error to problem details for the current request, with a title and detail in the user's language.
+func newProblem(c echo.Context, err *Error, message Message) Problem {
+	return Problem{
+		Type:      problemTypeBase + strings.ToLower(err.ErrorType),
+		Title:     message.Title,
+		Status:    err.StatusCode,
+		Detail:    message.Message,
+		Instance:  c.Request().URL.Path,
+		ErrorType: err.ErrorType,
+		Details:   err.Details,
+	}
+}
+
+// Returns true if errors should be returned as problem details.
+// Version 2 routes always return problem details, other routes only if the client accepts them.
+func wantsProblem(c echo.Context) bool {
+	path := c.Request().URL.Path
+	if path == apiV2Prefix || strings.HasPrefix(path, apiV2Prefix+"/") {
+		return true
+	}
+	return accepts(c, MIMEApplicationProblemJSON)
+}
+
+// Returns true if the Accept header explicitly lists mediaType with a non-zero quality.
+func accepts(c echo.Context, mediaType string) bool {
+	for _, accepted := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		accepted, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || accepted != mediaType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if quality, err := strconv.ParseFloat(q, 64); err != nil || quality == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Writes an API error as problem details.
+func writeProblem(c echo.Context, err *Error, message Message) error {
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(err.StatusCode, newProblem(c, err, message))
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/mail"
+	"github.com/IV1201-Group-2/login-service/metrics"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+type registerParams struct {
+	Name           string `form:"name"            json:"name"            validate:"required,max=255"`
+	Surname        string `form:"surname"         json:"surname"         validate:"required,max=255"`
+	PersonalNumber string `form:"personal_number" json:"personal_number" validate:"required,personal_number"`
+	Email          string `form:"email"           json:"email"           validate:"required,email,max=255"`
+	// Bcrypt ignores everything after 72 bytes
+	Password string `form:"password" json:"password" validate:"required,min=8,max=72"`
+}
+
+// Returns MISSING_PARAMETERS-style details for each identity that belongs to another user.
+func identityTaken(err error) ([]model.FieldError, bool) {
+	var fields []model.FieldError
+	if errors.Is(err, database.ErrEmailTaken) {
+		fields = append(fields, model.FieldError{Field: "email", Rule: model.FieldRuleUnique})
+	}
+	if errors.Is(err, database.ErrPersonalNumberTaken) {
+		fields = append(fields, model.FieldError{Field: "personal_number", Rule: model.FieldRuleUnique})
+	}
+	return fields, len(fields) > 0
+}
+
+// How long users that log in before verifying their e-mail address wait for a new link.
+// Otherwise every login with the correct password would mail another link.
+const verificationResendInterval = 10 * time.Minute
+
+// Sends a link that verifies the e-mail address of the user and records it in the audit trail.
+// Failing to send the link is logged but does not fail the request, since the user can ask for a new link by logging in.
+func sendVerification(c echo.Context, auditRepository *database.AuditRepository, mailer mail.Mailer, user model.User, auth *echojwt.Config, verifyURL string) {
+	if err := service.SendVerification(c.Request().Context(), mailer, user, auth.SigningKey, verifyURL); err != nil {
+		logging.Logcf(logrus.ErrorLevel, c, "Failed to send verification link to user %d: %v", user.ID, err)
+		return
+	}
+	recordUserEvent(c, auditRepository, user, model.AuthEventVerificationSent, metrics.OutcomeSuccess)
+	logging.Logcf(logrus.InfoLevel, c, "Sent verification link to user %d", user.ID)
+}
+
+// Sends a new verification link unless one was sent to the user within the resend interval.
+// Nothing is sent if mail is not configured.
+func resendVerification(c echo.Context, auditRepository *database.AuditRepository, mailer mail.Mailer, user model.User, auth *echojwt.Config, verifyURL string) {
+	if mailer == nil {
+		logging.Logcf(logrus.WarnLevel, c, "Can't send a new verification link to user %d, mail is not configured", user.ID)
+		return
+	}
+	since := time.Now().Add(-verificationResendInterval)
+	_, recent, err := auditRepository.List(c.Request().Context(), database.AuditFilter{
+		PersonID: &user.ID,
+		Type:     model.AuthEventVerificationSent,
+		From:     &since,
+		Limit:    1,
+	})
+	if err != nil {
+		logging.Logcf(logrus.ErrorLevel, c, "Failed to check previous verification links of user %d: %v", user.ID, err)
+		return
+	}
+	if recent > 0 {
+		logging.Logcf(logrus.InfoLevel, c, "Not resending verification link to user %d, the last one was sent less than %s ago", user.ID, verificationResendInterval)
+		return
+	}
+	sendVerification(c, auditRepository, mailer, user, auth, verifyURL)
+}
+
+// Registration route handler.
+// Creates an applicant that can log in once they have verified their e-mail address.
+// The response doesn't reveal whether the e-mail address or personal number already had an account.
+// Instead, the address is sent a notice about the existing account rather than a verification link.
+func Register(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, mailer mail.Mailer, registration bool, auth *echojwt.Config, verifyURL string) error {
+	if !registration || mailer == nil {
+		return ErrFeatureDisabled
+	}
+	// Check if user incorrectly provided a JWT token
+	if _, ok := userClaims(c); ok {
+		return ErrAlreadyLoggedIn
+	}
+
+	var params registerParams
+	// Check that all parameters are valid
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	logging.SetIdentity(c, params.Email)
+
+	ctx := c.Request().Context()
+	email := strings.TrimSpace(params.Email)
+	personalNumber, _ := service.NormalizePersonalNumber(params.PersonalNumber)
+	user, err := service.Register(ctx, userRepository, model.Registration{
+		Name:           strings.TrimSpace(params.Name),
+		Surname:        strings.TrimSpace(params.Surname),
+		PersonalNumber: personalNumber,
+		Email:          email,
+		Password:       params.Password,
+	})
+	if _, ok := identityTaken(err); ok {
+		logging.Logcf(logrus.WarnLevel, c, "Registration failed: identity already taken, sending a notice instead")
+		// Like the verification link, failing to send the notice must not change the response
+		if err := service.SendRegistrationNotice(ctx, mailer, email); err != nil {
+			logging.Logcf(logrus.ErrorLevel, c, "Failed to send registration notice: %v", err)
+		}
+		return c.JSON(http.StatusAccepted, model.RegistrationResponse{Email: email})
+	} else if err != nil {
+		return err
+	}
+	logging.SetUserID(c, user.ID)
+	logging.Logcf(logrus.InfoLevel, c, "Registered applicant %d", user.ID)
+
+	sendVerification(c, auditRepository, mailer, *user, auth, verifyURL)
+	return c.JSON(http.StatusAccepted, model.RegistrationResponse{Email: email})
+}
+
+type verifyParams struct {
+	Token string `query:"token" validate:"required"`
+}
+
+// E-mail verification route handler.
+// Users open this route through the link that was mailed to them.
+func VerifyEmail(c echo.Context, userRepository *database.UserRepository, auth *echojwt.Config) error {
+	var params verifyParams
+	// Check that all parameters are present
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	claims, err := service.ParseToken(ctx, params.Token, auth.SigningKey)
+	if err != nil {
+		logging.Logcf(logrus.WarnLevel, c, "Verification failed: %v", err)
+		return ErrTokenInvalid
+	}
+	logging.SetUserID(c, claims.User.ID)
+	logging.SetIdentity(c, claims.User.Email)
+
+	user, err := service.VerifyEmail(ctx, userRepository, *claims)
+	if fields, ok := identityTaken(err); ok {
+		logging.Logcf(logrus.WarnLevel, c, "E-mail change failed: identity already taken")
+		return ErrIdentityTaken.WithDetails(fields)
+	}
+	switch {
+	case errors.Is(err, service.ErrWrongUsage):
+		logging.Logcf(logrus.WarnLevel, c, "Verification failed: user provided a %s token", claims.Usage)
+		return ErrTokenInvalid
+	case errors.Is(err, service.ErrUserDisabled):
+		logging.Logcf(logrus.WarnLevel, c, "Verification failed: user %d has been disabled", claims.User.ID)
+		return ErrAccountDisabled
+	case errors.Is(err, database.ErrUserNotFound):
+		// The user was deleted or has changed e-mail address since the link was sent
+		logging.Logcf(logrus.WarnLevel, c, "Verification failed: user %d no longer has the e-mail address", claims.User.ID)
+		return ErrTokenInvalid
+	case err != nil:
+		return err
+	}
+	if claims.Usage == model.TokenUsageEmailChange {
+		logging.Logcf(logrus.InfoLevel, c, "User %d has changed their e-mail address", user.ID)
+	} else {
+		logging.Logcf(logrus.InfoLevel, c, "User %d has verified their e-mail address", user.ID)
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+type emailParams struct {
+	CurrentPassword string `form:"current_password" json:"current_password" validate:"required"`
+	Email           string `form:"email"            json:"email"            validate:"required,email,max=255"`
+}
+
+// E-mail change route handler.
+// Mails a link that changes the e-mail address of the logged in user to the new address, and a notice to the current address.
+func ChangeEmail(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, lockout *lockout, mailer mail.Mailer, auth *echojwt.Config, verifyURL string) error {
+	if mailer == nil {
+		return ErrFeatureDisabled
+	}
+	claims, err := requireLogin(c)
+	if err != nil {
+		return err
+	}
+	logging.SetUserID(c, claims.User.ID)
+
+	var params emailParams
+	// Check that all parameters are valid
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	email := strings.TrimSpace(params.Email)
+
+	// Guessing the current password is the same as guessing it at login
+	if err := lockout.check(c, auditRepository, claims.User.ID); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	user, err := service.RequestEmailChange(ctx, userRepository, *claims, params.CurrentPassword, email)
+	if fields, ok := identityTaken(err); ok {
+		logging.Logcf(logrus.WarnLevel, c, "E-mail change failed: identity already taken")
+		return ErrIdentityTaken.WithDetails(fields)
+	}
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: wrong current password for user %d", claims.User.ID)
+		return ErrWrongIdentity
+	case errors.Is(err, service.ErrUserDisabled):
+		logging.Logcf(logrus.WarnLevel, c, "E-mail change failed: user %d has been disabled", claims.User.ID)
+		return ErrAccountDisabled
+	case errors.Is(err, database.ErrUserNotFound):
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user %d no longer exists", claims.User.ID)
+		return ErrTokenInvalid
+	case err != nil:
+		return err
+	}
+
+	// Unlike registration, the user is still waiting for the link and should be told if it couldn't be sent
+	if err := service.SendEmailChange(ctx, mailer, *user, email, auth.SigningKey, verifyURL); err != nil {
+		return err
+	}
+	logging.Logcf(logrus.InfoLevel, c, "Sent e-mail change link to user %d", user.ID)
+
+	return c.JSON(http.StatusAccepted, model.EmailChangeResponse{Email: email})
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/mail"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSecretPollInterval is how often secret files are checked for changes.
+const DefaultSecretPollInterval = 5 * time.Second
+
+// Key used to store the resources of the current request in the Echo context.
+const resourcesKey = "api.resources"
+
+// The database pool and signing key used to handle requests.
+// Resources are never modified; reloading secrets replaces them as a whole.
+type resources struct {
+	db              *sql.DB
+	databaseURL     string
+	auth            *echojwt.Config
+	jwt             echo.MiddlewareFunc
+	auditRepository *database.AuditRepository
+	// Users and sessions that tokens belong to, also checked by the jwt middleware
+	userRepository    *database.UserRepository
+	sessionRepository *database.SessionRepository
+	// Sends links to users, nil if mail is not configured
+	mailer mail.Mailer
+	// Applicants can register themselves
+	registration bool
+	// Links that e-mail verification and password reset tokens are added to
+	verifyURL string
+	resetURL  string
+	// Cookie that login tokens are sent in, nil unless cookie mode is enabled
+	cookie *tokenCookie
+	// Locks accounts after too many wrong passwords, nil if lockout is disabled
+	lockout *lockout
+
+	// Number of requests using the resources. Once retired and no longer in use, drained is closed.
+	mu      sync.Mutex
+	active  int
+	retired bool
+	drained chan struct{}
+}
+
+func newResources(db *sql.DB, cfg *config.Config) (*resources, error) {
+	userRepository := database.NewUserRepository(db)
+	sessionRepository := database.NewSessionRepository(db)
+	auth, err := NewAuthConfig(cfg.Auth, userRepository, sessionRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &resources{
+		db:                db,
+		databaseURL:       cfg.Database.URL,
+		auth:              auth,
+		jwt:               echojwt.WithConfig(*auth),
+		userRepository:    userRepository,
+		auditRepository:   database.NewAuditRepository(db),
+		sessionRepository: sessionRepository,
+		mailer:            mail.New(cfg.Mail),
+		registration:      cfg.Auth.Registration,
+		verifyURL:         mailLink(cfg, cfg.Mail.VerifyURL, "/verify"),
+		resetURL:          mailLink(cfg, cfg.Mail.ResetURL, "/reset"),
+		cookie:            newTokenCookie(cfg.Auth),
+		lockout:           newLockout(cfg.Auth),
+		drained:           make(chan struct{}),
+	}, nil
+}
+
+// Returns the configured page that mailed tokens are sent to, such as the e-mail verification page.
+// Pages are required when e-mail is sent through SMTP, so links only point to the route of this service
+// on localhost when e-mail is written to the log during development.
+func mailLink(cfg *config.Config, page string, route string) string {
+	if page != "" || !cfg.Mail.Log {
+		return page
+	}
+	scheme := "http"
+	if cfg.TLS.Enabled() {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://localhost:%s%s%s", scheme, cfg.Port, apiV1Prefix, route)
+}
+
+// Marks the resources as in use. Returns false if they have been retired.
+func (r *resources) acquire() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.retired {
+		return false
+	}
+	r.active++
+	return true
+}
+
+func (r *resources) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active--
+	if r.retired && r.active == 0 {
+		close(r.drained)
+	}
+}
+
+// Prevents new requests from using the resources.
+// drained is closed once all requests that are using them have finished.
+func (r *resources) retire() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retired = true
+	if r.active == 0 {
+		close(r.drained)
+	}
+}
+
+// Returns the resources acquired for the current request.
+func currentResources(c echo.Context) *resources {
+	return c.Get(resourcesKey).(*resources)
+}
+
+// Reloader replaces the database pool and signing key of a running server.
+// Requests that are in flight during a reload finish with the old pool and key.
+type Reloader struct {
+	current atomic.Pointer[resources]
+	// Only one reload can run at a time
+	mu sync.Mutex
+	// Files that secrets were read from and when they were last modified
+	secretFiles map[string]time.Time
+}
+
+func newReloader(db *sql.DB, cfg *config.Config) (*Reloader, error) {
+	res, err := newResources(db, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reloader{}
+	r.current.Store(res)
+	r.secretFiles = modTimes(cfg.SecretFiles)
+	return r, nil
+}
+
+// Returns when each file was last modified. Missing files are included with a zero time.
+func modTimes(paths []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		} else {
+			times[path] = time.Time{}
+		}
+	}
+	return times
+}
+
+// Middleware that acquires the current resources for the duration of a request
+// and validates the JWT token with the current signing key.
+func (r *Reloader) middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			res := r.current.Load()
+			// The resources might be retired between loading and acquiring them
+			for !res.acquire() {
+				next := r.current.Load()
+				if next == res {
+					// The reloader has been closed
+					return ErrServiceUnavailable
+				}
+				res = next
+			}
+			defer res.release()
+
+			c.Set(resourcesKey, res)
+			return res.jwt(next)(c)
+		}
+	}
+}
+
+// Swap makes the server use db and the signing key in cfg for new requests.
+// The old database pool is closed once in-flight requests have finished with it, unless it is db.
+func (r *Reloader) Swap(db *sql.DB, cfg *config.Config) error {
+	res, err := newResources(db, cfg)
+	if err != nil {
+		return err
+	}
+
+	old := r.current.Swap(res)
+	old.retire()
+	if old.db != db {
+		go func() {
+			<-old.drained
+			if err := database.Close(old.db); err != nil {
+				logging.Logf(logrus.WarnLevel, "Failed to close old database pool: %v", err)
+			}
+			logging.Logf(logrus.DebugLevel, "Closed old database pool")
+		}()
+	}
+	return nil
+}
+
+// Reload loads the configuration again and swaps in the new signing key and database credentials.
+// A new database pool is only opened if the database URL has changed.
+// If the new configuration is invalid or the database can't be reached, the server keeps its current resources.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Don't retry until the files change again, even if the reload fails
+	r.secretFiles = modTimes(mapKeys(r.secretFiles))
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	r.secretFiles = modTimes(cfg.SecretFiles)
+
+	current := r.current.Load()
+	db := current.db
+	if cfg.Database.URL != current.databaseURL {
+		if db, err = database.Open(cfg.Database); err != nil {
+			return err
+		}
+	}
+
+	if err := r.Swap(db, cfg); err != nil {
+		if db != current.db {
+			database.Close(db)
+		}
+		return err
+	}
+
+	logging.Logf(logrus.InfoLevel, "Reloaded secrets (new database pool: %t)", db != current.db)
+	return nil
+}
+
+// Returns true if any secret file has been modified since it was last read.
+func (r *Reloader) secretsChanged() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for path, modTime := range modTimes(mapKeys(r.secretFiles)) {
+		if !modTime.Equal(r.secretFiles[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+func mapKeys(m map[string]time.Time) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Watch reloads secrets when the process receives SIGHUP or when a secret file changes.
+// Secret files are checked every interval. Watch returns when ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			logging.Logf(logrus.InfoLevel, "Reloading secrets after SIGHUP")
+		case <-ticker.C:
+			if !r.secretsChanged() {
+				continue
+			}
+			logging.Logf(logrus.InfoLevel, "Reloading secrets after a secret file changed")
+		}
+
+		if err := r.Reload(); err != nil {
+			logging.Logf(logrus.ErrorLevel, "Failed to reload secrets, keeping current secrets: %v", err)
+		}
+	}
+}
+
+// Close waits for in-flight requests to finish and closes the current database pool.
+// If ctx is done first, the pool is closed anyway and the context error is returned.
+// This should be called after the server has shut down.
+func (r *Reloader) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := r.current.Load()
+	res.retire()
+	select {
+	case <-res.drained:
+		return database.Close(res.db)
+	case <-ctx.Done():
+		return errors.Join(ctx.Err(), database.Close(res.db))
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/IV1201-Group-2/login-service/database"
 	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/mail"
+	"github.com/IV1201-Group-2/login-service/metrics"
 	"github.com/IV1201-Group-2/login-service/model"
 	"github.com/IV1201-Group-2/login-service/service"
 	"github.com/golang-jwt/jwt/v5"
@@ -22,7 +24,7 @@ type loginParams struct {
 }
 
 // Login route handler.
-func Login(c echo.Context, userRepository *database.UserRepository, auth *echojwt.Config) error {
+func Login(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, sessionRepository *database.SessionRepository, cookie *tokenCookie, lockout *lockout, mailer mail.Mailer, auth *echojwt.Config, verifyURL string) error {
 	// Check if user incorrectly provided a JWT token
 	_, ok := c.Get("user").(*jwt.Token)
 	if ok {
@@ -31,22 +33,40 @@ func Login(c echo.Context, userRepository *database.UserRepository, auth *echojw
 
 	var params loginParams
 	// Check that all parameters are present
-	if err := errors.Join(c.Bind(&params), c.Validate(&params)); err != nil {
-		return ErrMissingParameters
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	logging.SetIdentity(c, params.Identity)
+
+	if err := lockout.checkIdentity(c, userRepository, auditRepository, params.Identity); err != nil {
+		return err
 	}
 
-	user, err := service.AuthenticateUser(userRepository, params.Identity, params.Password, params.Role)
+	ctx := c.Request().Context()
+	user, err := service.AuthenticateUser(ctx, userRepository, params.Identity, params.Password, params.Role)
+	if user != nil {
+		logging.SetUserID(c, user.ID)
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrMissingPassword):
 			// Create a new reset token allowing the user to reset their password
-			token, expiry, err := service.SignResetToken(*user, auth.SigningKey)
+			token, expiry, err := service.SignResetToken(ctx, *user, auth.SigningKey)
 			if err != nil {
 				return err
 			}
+			recordEvent(c, auditRepository, model.AuthEventResetTokenIssued, metrics.OutcomeSuccess)
 			logging.Logcf(logrus.WarnLevel, c, "Login failed: user has no password in db")
 			logging.Logcf(logrus.InfoLevel, c, "Handed out reset token that expires at %s", expiry.Format(logging.TimestampFormat))
 			return ErrMissingPassword.WithDetails(model.ResetTokenResponse{Token: token})
+		case errors.Is(err, service.ErrUserDisabled):
+			logging.Logcf(logrus.WarnLevel, c, "Login failed: user '%s' has been disabled", params.Identity)
+			return ErrAccountDisabled
+		case errors.Is(err, service.ErrEmailUnverified):
+			// The previous link may have expired or never arrived
+			logging.Logcf(logrus.WarnLevel, c, "Login failed: user '%s' has not verified their e-mail address", params.Identity)
+			resendVerification(c, auditRepository, mailer, *user, auth, verifyURL)
+			return ErrEmailNotVerified
 		case errors.Is(err, service.ErrWrongIdentity):
 			logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user '%s' not found", params.Identity)
 			return ErrWrongIdentity
@@ -59,11 +79,10 @@ func Login(c echo.Context, userRepository *database.UserRepository, auth *echojw
 	}
 
 	// Create a new token valid for the auth expiry period
-	token, expiry, err := service.SignUserToken(*user, auth.SigningKey)
+	token, err := issueLoginToken(c, sessionRepository, auditRepository, cookie, *user, auth)
 	if err != nil {
 		return err
 	}
-	logging.Logcf(logrus.InfoLevel, c, "Login successful: token expires at %s", expiry.Format(logging.TimestampFormat))
 
 	return c.JSON(http.StatusOK, model.LoginTokenResponse{Token: token})
 }
@@ -73,7 +92,7 @@ type resetParams struct {
 }
 
 // Password reset route handler.
-func PasswordReset(c echo.Context, userRepository *database.UserRepository, auth *echojwt.Config) error {
+func PasswordReset(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, sessionRepository *database.SessionRepository, cookie *tokenCookie, auth *echojwt.Config) error {
 	// Check if user provided a token
 	token, ok := c.Get("user").(*jwt.Token)
 	if !ok {
@@ -83,12 +102,13 @@ func PasswordReset(c echo.Context, userRepository *database.UserRepository, auth
 
 	var params resetParams
 	// Check that all parameters are present
-	if err := errors.Join(c.Bind(&params), c.Validate(&params)); err != nil {
-		return ErrMissingParameters
+	if err := bindParams(c, &params); err != nil {
+		return err
 	}
 
+	ctx := c.Request().Context()
 	claims, _ := token.Claims.(*model.UserClaims)
-	err := service.UpdatePassword(userRepository, *claims, params.Password)
+	err := service.UpdatePassword(ctx, userRepository, *claims, params.Password)
 	if errors.Is(err, service.ErrWrongUsage) {
 		return ErrTokenInvalid
 	} else if err != nil {
@@ -102,11 +122,62 @@ func PasswordReset(c echo.Context, userRepository *database.UserRepository, auth
 	}
 
 	// Create a new token valid for the auth expiry period
-	newToken, expiry, err := service.SignUserToken(claims.User, auth.SigningKey)
+	newToken, err := issueLoginToken(c, sessionRepository, auditRepository, cookie, claims.User, auth)
 	if err != nil {
 		return err
 	}
-	logging.Logcf(logrus.InfoLevel, c, "Login successful: token expires at %s", expiry.Format(logging.TimestampFormat))
 
 	return c.JSON(http.StatusOK, model.LoginTokenResponse{Token: newToken})
 }
+
+type passwordParams struct {
+	CurrentPassword string `form:"current_password" json:"current_password" validate:"required"`
+	Password        string `form:"password"         json:"password"         validate:"required"`
+}
+
+// Password change route handler.
+// Logged in users can choose a new password if they provide their current one.
+func ChangePassword(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, sessionRepository *database.SessionRepository, cookie *tokenCookie, lockout *lockout, auth *echojwt.Config) error {
+	claims, err := requireLogin(c)
+	if err != nil {
+		return err
+	}
+	logging.SetUserID(c, claims.User.ID)
+
+	var params passwordParams
+	// Check that all parameters are present
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	// Guessing the current password is the same as guessing it at login
+	if err := lockout.check(c, auditRepository, claims.User.ID); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	user, err := service.ChangePassword(ctx, userRepository, *claims, params.CurrentPassword, params.Password)
+	switch {
+	case errors.Is(err, service.ErrWrongPassword):
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: wrong current password for user %d", claims.User.ID)
+		return ErrWrongIdentity
+	case errors.Is(err, service.ErrUserDisabled):
+		logging.Logcf(logrus.WarnLevel, c, "Password change failed: user %d has been disabled", claims.User.ID)
+		return ErrAccountDisabled
+	case errors.Is(err, database.ErrUserNotFound):
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user %d no longer exists", claims.User.ID)
+		return ErrTokenInvalid
+	case err != nil:
+		return err
+	}
+	logging.Logcf(logrus.InfoLevel, c, "User %d has changed password", user.ID)
+
+	// Create a new token valid for the auth expiry period.
+	// The password change revoked every other session, which should not keep working with the old password.
+	token, err := issueLoginToken(c, sessionRepository, auditRepository, cookie, *user, auth)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.LoginTokenResponse{Token: token})
+}
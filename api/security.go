@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// Browsers remember to only use HTTPS for two years.
+const hstsMaxAge = 2 * 365 * 24 * 60 * 60
+
+// The API only returns JSON, so pages are not allowed to load anything or be framed.
+const contentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+
+// Names of the CSRF cookie and the form field or header that must contain the same token.
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFFormField  = "csrf_token"
+	CSRFHeader     = echo.HeaderXCSRFToken
+)
+
+// Key used to store the CSRF token in the Echo context.
+const csrfKey = "api.csrf"
+
+// Path of the route that hands out CSRF tokens.
+const csrfPath = "/api/csrf"
+
+// SecurityHeadersMiddleware sets headers that protect browsers using the API.
+// HSTS is only sent over HTTPS, either directly or through a proxy that sets X-Forwarded-Proto.
+func SecurityHeadersMiddleware() echo.MiddlewareFunc {
+	return middleware.SecureWithConfig(middleware.SecureConfig{
+		ContentTypeNosniff:    "nosniff",
+		XFrameOptions:         "DENY",
+		HSTSMaxAge:            hstsMaxAge,
+		ContentSecurityPolicy: contentSecurityPolicy,
+		ReferrerPolicy:        "no-referrer",
+	})
+}
+
+// Middleware that prevents responses containing tokens from being stored by browsers and proxies.
+func noStore(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		c.Response().Header().Set("Pragma", "no-cache")
+		return next(c)
+	}
+}
+
+// Returns true if the request body is a form, which browsers can submit from any site without a preflight request.
+func isFormSubmission(c echo.Context) bool {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	return strings.HasPrefix(contentType, echo.MIMEApplicationForm) ||
+		strings.HasPrefix(contentType, echo.MIMEMultipartForm) ||
+		strings.HasPrefix(contentType, echo.MIMETextPlain)
+}
+
+// CSRFMiddleware protects form submissions and requests authenticated by the token cookie against cross-site request forgery.
+// A request is accepted if the Origin header is this site or an origin allowed by CORS,
+// or if it contains a CSRF token matching the CSRF cookie (double-submit).
+// Other JSON requests can't be sent cross-site without a CORS preflight and are not checked.
+func CSRFMiddleware(cfg *config.Config) echo.MiddlewareFunc {
+	allowedOrigin := originMatcher(cfg.CORS.AllowOrigins)
+
+	return middleware.CSRFWithConfig(middleware.CSRFConfig{
+		Skipper: func(c echo.Context) bool {
+			// Tokens are only handed out by the CSRF route
+			if c.Path() == csrfPath {
+				return false
+			}
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return true
+			}
+			// Browsers attach the token cookie to requests from any site, whatever the content type
+			if !isFormSubmission(c) && !cookieAuthenticated(c) {
+				return true
+			}
+			origin := c.Request().Header.Get(echo.HeaderOrigin)
+			return origin != "" && (sameOrigin(c, origin) || allowedOrigin(origin))
+		},
+		TokenLookup:    "form:" + CSRFFormField + ",header:" + CSRFHeader,
+		ContextKey:     csrfKey,
+		CookieName:     CSRFCookieName,
+		CookiePath:     "/",
+		CookieSecure:   cfg.TLS.Enabled(),
+		CookieHTTPOnly: true,
+		CookieSameSite: http.SameSiteStrictMode,
+		ErrorHandler: func(err error, c echo.Context) error {
+			logging.Logcf(logrus.WarnLevel, c, "Rejected cross-site request from origin '%s': %v",
+				c.Request().Header.Get(echo.HeaderOrigin), err)
+			return ErrCSRFFailed
+		},
+	})
+}
+
+// CSRFToken route handler.
+// Returns a token that must be included in forms, and sets the matching cookie.
+func CSRFToken(c echo.Context) error {
+	token, _ := c.Get(csrfKey).(string)
+	return c.JSON(http.StatusOK, model.CSRFTokenResponse{Token: token})
+}
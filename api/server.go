@@ -2,38 +2,156 @@ package api
 
 import (
 	"database/sql"
+	"net/http"
+	"time"
 
-	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/config"
 	"github.com/IV1201-Group-2/login-service/logging"
-	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/IV1201-Group-2/login-service/metrics"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tracing"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
+// Prefixes of the API versions.
+// Both versions have the same routes, but version 2 returns errors as RFC 7807 problem details.
+const (
+	apiV1Prefix = "/api"
+	apiV2Prefix = "/api/v2"
+)
+
+// Path that Prometheus metrics are served at.
+const metricsPath = "/metrics"
+
+// Timeout for reading request headers on the metrics listener.
+const metricsReadHeaderTimeout = 10 * time.Second
+
 // NewServer creates a new Echo server instance for the login REST API.
-func NewServer(db *sql.DB) (*echo.Echo, error) {
+// The returned Reloader replaces the database pool and signing key while the server is running.
+func NewServer(db *sql.DB, cfg *config.Config) (*echo.Echo, *Reloader, error) {
 	srv := echo.New()
 	srv.HTTPErrorHandler = ErrorHandler
 	srv.Validator = NewValidator()
 
-	srv.Use(logging.Middleware())
+	reloader, err := newReloader(db, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srv.Use(tracing.Middleware())
+	srv.Use(logging.RequestIDMiddleware())
+	// Health probes are requested frequently and would flood the logs
+	srv.Use(logging.Middleware("/healthz", "/readyz"))
+	srv.Use(metrics.Middleware())
 	srv.Use(middleware.Recover())
-	srv.Use(middleware.CORS())
+	srv.Use(CORSMiddleware(cfg.CORS))
+	srv.Use(SecurityHeadersMiddleware())
+	srv.Use(CSRFMiddleware(cfg))
+	// Every request uses the same database pool and signing key from start to finish
+	srv.Use(reloader.middleware())
 
-	userRepository := database.NewUserRepository(db)
+	registerAPI(srv.Group(apiV1Prefix))
+	registerAPI(srv.Group(apiV2Prefix))
+	srv.GET(csrfPath, CSRFToken, noStore)
+	srv.GET(openAPIPath, OpenAPI)
+	srv.GET("/healthz", Liveness)
+	srv.GET("/readyz", func(c echo.Context) error {
+		res := currentResources(c)
+		return Readiness(c, res.db, res.auth)
+	})
 
-	authConfig, err := NewAuthConfig()
-	if err != nil {
-		return nil, err
+	return srv, reloader, nil
+}
+
+// NewMetricsServer creates a server that only serves Prometheus metrics at /metrics on address.
+// Metrics are never served by the API, so that they can be kept out of reach of API clients.
+func NewMetricsServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, metrics.Handler())
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: metricsReadHeaderTimeout,
 	}
-	srv.Use(echojwt.WithConfig(*authConfig))
+}
 
-	srv.POST("/api/login", func(c echo.Context) error {
-		return Login(c, userRepository, authConfig)
+// Registers the routes of an API version.
+func registerAPI(g *echo.Group) {
+	g.POST("/login", func(c echo.Context) error {
+		res := currentResources(c)
+		err := Login(c, res.userRepository, res.auditRepository, res.sessionRepository, res.cookie, res.lockout, res.mailer, res.auth, res.verifyURL)
+		metrics.LoginOutcomes.WithLabelValues(outcome(err)).Inc()
+		recordEvent(c, res.auditRepository, model.AuthEventLogin, outcome(err))
+		return err
+	}, noStore)
+	g.POST("/reset", func(c echo.Context) error {
+		res := currentResources(c)
+		err := PasswordReset(c, res.userRepository, res.auditRepository, res.sessionRepository, res.cookie, res.auth)
+		metrics.ResetOutcomes.WithLabelValues(outcome(err)).Inc()
+		recordEvent(c, res.auditRepository, model.AuthEventReset, outcome(err))
+		return err
+	}, noStore)
+	g.POST("/register", func(c echo.Context) error {
+		res := currentResources(c)
+		return Register(c, res.userRepository, res.auditRepository, res.mailer, res.registration, res.auth, res.verifyURL)
 	})
-	srv.POST("/api/reset", func(c echo.Context) error {
-		return PasswordReset(c, userRepository, authConfig)
+	g.GET("/verify", func(c echo.Context) error {
+		res := currentResources(c)
+		return VerifyEmail(c, res.userRepository, res.auth)
+	})
+	g.POST("/password", func(c echo.Context) error {
+		res := currentResources(c)
+		err := ChangePassword(c, res.userRepository, res.auditRepository, res.sessionRepository, res.cookie, res.lockout, res.auth)
+		metrics.PasswordChangeOutcomes.WithLabelValues(outcome(err)).Inc()
+		recordEvent(c, res.auditRepository, model.AuthEventPasswordChange, outcome(err))
+		return err
+	}, noStore)
+	g.POST("/email", func(c echo.Context) error {
+		res := currentResources(c)
+		err := ChangeEmail(c, res.userRepository, res.auditRepository, res.lockout, res.mailer, res.auth, res.verifyURL)
+		metrics.EmailChangeOutcomes.WithLabelValues(outcome(err)).Inc()
+		recordEvent(c, res.auditRepository, model.AuthEventEmailChange, outcome(err))
+		return err
+	})
+	g.POST("/logout", func(c echo.Context) error {
+		res := currentResources(c)
+		return Logout(c, res.sessionRepository, res.cookie)
+	})
+	g.GET("/sessions", func(c echo.Context) error {
+		res := currentResources(c)
+		return ListSessions(c, res.sessionRepository)
+	})
+	g.POST("/sessions/:id/revoke", func(c echo.Context) error {
+		res := currentResources(c)
+		return RevokeSession(c, res.sessionRepository)
+	})
+	g.GET("/audit", func(c echo.Context) error {
+		res := currentResources(c)
+		return ListAuditEvents(c, res.auditRepository)
+	})
+	g.GET("/users", func(c echo.Context) error {
+		res := currentResources(c)
+		return ListUsers(c, res.userRepository, res.auditRepository, res.lockout)
+	})
+	g.GET("/users/:id", func(c echo.Context) error {
+		res := currentResources(c)
+		return GetUser(c, res.userRepository, res.auditRepository, res.lockout)
+	})
+	g.POST("/users/:id/reset", func(c echo.Context) error {
+		res := currentResources(c)
+		return ResetUser(c, res.userRepository, res.auditRepository, res.sessionRepository, res.lockout, res.mailer, res.auth, res.resetURL)
+	}, noStore)
+	g.POST("/users/:id/role", func(c echo.Context) error {
+		res := currentResources(c)
+		return UpdateUserRole(c, res.userRepository, res.auditRepository, res.lockout)
+	})
+	g.POST("/users/:id/disable", func(c echo.Context) error {
+		res := currentResources(c)
+		return SetUserDisabled(c, res.userRepository, res.auditRepository, res.lockout, true)
+	})
+	g.POST("/users/:id/enable", func(c echo.Context) error {
+		res := currentResources(c)
+		return SetUserDisabled(c, res.userRepository, res.auditRepository, res.lockout, false)
 	})
-
-	return srv, nil
 }
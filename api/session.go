@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/metrics"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Starts a session for the user on the device that made the request and returns its login token.
+// In cookie mode, the token is also set in the token cookie.
+func issueLoginToken(c echo.Context, sessionRepository *database.SessionRepository, auditRepository *database.AuditRepository, cookie *tokenCookie, user model.User, auth *echojwt.Config) (string, error) {
+	request := c.Request()
+	token, expiry, err := service.StartSession(request.Context(), sessionRepository, user, request.UserAgent(), c.RealIP(), auth.SigningKey)
+	if err != nil {
+		return "", err
+	}
+	cookie.set(c, token, expiry)
+	recordEvent(c, auditRepository, model.AuthEventLoginTokenIssued, metrics.OutcomeSuccess)
+	logging.Logcf(logrus.InfoLevel, c, "Login successful: token expires at %s", expiry.Format(logging.TimestampFormat))
+
+	return token, nil
+}
+
+// Session list route handler.
+// Lists the devices that the logged in user is logged in on.
+func ListSessions(c echo.Context, sessionRepository *database.SessionRepository) error {
+	claims, err := requireLogin(c)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := service.ListSessions(c.Request().Context(), sessionRepository, *claims)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.SessionList{Sessions: sessions})
+}
+
+type sessionParams struct {
+	ID string `param:"id" json:"-" form:"-" validate:"required,max=64"`
+}
+
+// Session revocation route handler.
+// Logs the user out of one of their sessions, which can be the current one. Login tokens of the session stop working immediately.
+func RevokeSession(c echo.Context, sessionRepository *database.SessionRepository) error {
+	claims, err := requireLogin(c)
+	if err != nil {
+		return err
+	}
+
+	var params sessionParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	err = service.RevokeSession(ctx, sessionRepository, *claims, params.ID)
+	if errors.Is(err, database.ErrSessionNotFound) {
+		return ErrSessionNotFound
+	} else if err != nil {
+		return err
+	}
+	logging.Logcf(logrus.InfoLevel, c, "User %d revoked session %s", claims.User.ID, params.ID)
+
+	// Respond with the sessions that are left
+	sessions, err := service.ListSessions(ctx, sessionRepository, *claims)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.SessionList{Sessions: sessions})
+}
+
+// Logout route handler.
+// Revokes the session of the login token, if it belongs to one, and removes the token cookie.
+// Logging out without a token succeeds, so that browsers can always get rid of the cookie.
+func Logout(c echo.Context, sessionRepository *database.SessionRepository, cookie *tokenCookie) error {
+	if claims, ok := userClaims(c); ok && claims.Usage == model.TokenUsageLogin && claims.SessionID != "" {
+		err := service.RevokeSession(c.Request().Context(), sessionRepository, *claims, claims.SessionID)
+		if err != nil && !errors.Is(err, database.ErrSessionNotFound) {
+			return err
+		}
+		logging.Logcf(logrus.InfoLevel, c, "User %d logged out of session %s", claims.User.ID, claims.SessionID)
+	}
+	cookie.clear(c)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Timeout for reading request headers on the HTTPS redirect listener.
+const redirectReadHeaderTimeout = 10 * time.Second
+
+// TLSOptions configures how ServeTLS serves HTTPS.
+type TLSOptions struct {
+	// TLS configuration with the certificate to serve, usually created by NewTLSConfig.
+	Config *tls.Config
+	// If set, plain HTTP requests to this address are redirected to HTTPS.
+	RedirectAddress string
+}
+
+// Serve starts the server and blocks until it receives SIGTERM or SIGINT.
+// The server then stops accepting connections and waits up to timeout for in-flight requests to finish.
+// Other servers, such as the one created by NewMetricsServer, are started and shut down together with it.
+func Serve(srv *echo.Echo, address string, timeout time.Duration, servers ...*http.Server) error {
+	return serve(srv, timeout, func() error { return srv.Start(address) }, servers)
+}
+
+// ServeTLS is like Serve, but serves HTTPS and optionally redirects plain HTTP requests to HTTPS.
+func ServeTLS(srv *echo.Echo, address string, timeout time.Duration, options TLSOptions, servers ...*http.Server) error {
+	srv.TLSServer.Addr = address
+	srv.TLSServer.TLSConfig = options.Config
+
+	if options.RedirectAddress != "" {
+		_, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		servers = append(servers, &http.Server{
+			Addr:              options.RedirectAddress,
+			Handler:           RedirectHandler(port),
+			ReadHeaderTimeout: redirectReadHeaderTimeout,
+		})
+	}
+
+	return serve(srv, timeout, func() error { return srv.StartServer(srv.TLSServer) }, servers)
+}
+
+// Runs the server started by start, and the other plain HTTP servers, until SIGTERM or SIGINT is received.
+// If one of the other servers fails, the remaining servers are shut down as well.
+// All servers are shut down even if some fail, and their errors are returned together.
+func serve(srv *echo.Echo, timeout time.Duration, start func() error, servers []*http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- start()
+	}()
+	serverErrs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serverErrs <- fmt.Errorf("server on %s: %w", server.Addr, err)
+			}
+		}()
+	}
+
+	var failures []error
+	select {
+	case err := <-errs:
+		// Server failed to start or stopped on its own
+		failures = append(failures, err)
+		for _, server := range servers {
+			if err := server.Close(); err != nil {
+				failures = append(failures, fmt.Errorf("server on %s: %w", server.Addr, err))
+			}
+		}
+		return errors.Join(failures...)
+	case err := <-serverErrs:
+		logging.Logf(logrus.ErrorLevel, "Server error: %v", err)
+		failures = append(failures, err)
+	case <-ctx.Done():
+	}
+
+	logging.Logf(logrus.InfoLevel, "Shutting down, waiting up to %s for requests to finish", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			failures = append(failures, fmt.Errorf("server on %s: %w", server.Addr, err))
+		}
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		failures = append(failures, err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		failures = append(failures, err)
+	}
+	// Other servers may have failed while shutting down
+	for {
+		select {
+		case err := <-serverErrs:
+			failures = append(failures, err)
+		default:
+			return errors.Join(failures...)
+		}
+	}
+}
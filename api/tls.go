@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Only TLS 1.2 cipher suites with forward secrecy and authenticated encryption are allowed.
+// TLS 1.3 cipher suites are not configurable and are all secure.
+var tlsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Certificate is a TLS certificate that is loaded from disk and reloaded when the files change.
+type Certificate struct {
+	certFile string
+	keyFile  string
+	current  atomic.Pointer[tls.Certificate]
+
+	// Only one reload can run at a time
+	mu       sync.Mutex
+	modTimes map[string]time.Time
+}
+
+// LoadCertificate loads a PEM-encoded certificate chain and private key.
+func LoadCertificate(certFile string, keyFile string) (*Certificate, error) {
+	c := &Certificate{certFile: certFile, keyFile: keyFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload loads the certificate from disk again. If loading fails, the current certificate is kept.
+func (c *Certificate) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Don't retry until the files change again, even if loading fails
+	c.modTimes = modTimes([]string{c.certFile, c.keyFile})
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	c.current.Store(&cert)
+	return nil
+}
+
+// GetCertificate returns the current certificate. It is used as tls.Config.GetCertificate.
+func (c *Certificate) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.current.Load(), nil
+}
+
+// Returns true if the certificate or key file has been modified since it was last loaded.
+func (c *Certificate) changed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for path, modTime := range modTimes([]string{c.certFile, c.keyFile}) {
+		if !modTime.Equal(c.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch reloads the certificate when the certificate or key file changes.
+// The files are checked every interval. Watch returns when ctx is cancelled.
+func (c *Certificate) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !c.changed() {
+			continue
+		}
+
+		// Certificate and key are often replaced one after the other, so a mismatch is retried on the next change
+		if err := c.Reload(); err != nil {
+			logging.Logf(logrus.ErrorLevel, "Failed to reload certificate, keeping current certificate: %v", err)
+			continue
+		}
+		logging.Logf(logrus.InfoLevel, "Reloaded certificate")
+	}
+}
+
+// NewTLSConfig creates a TLS configuration that serves cert with a secure minimum version and cipher suites.
+func NewTLSConfig(cert *Certificate) *tls.Config {
+	return &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CipherSuites:     tlsCipherSuites,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		GetCertificate:   cert.GetCertificate,
+		NextProtos:       []string{"h2", "http/1.1"},
+	}
+}
+
+// RedirectHandler redirects GET and HEAD requests to the same host and path over HTTPS on httpsPort.
+// Other requests may already have sent credentials in the clear, so they get ErrHTTPSRequired
+// instead of a redirect that would make the client send them again.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			message, lang := LocalizedMessage(ErrHTTPSRequired, r.Header.Get("Accept-Language"))
+			localized := *ErrHTTPSRequired
+			localized.Message = message.Message
+			w.Header().Set("Content-Language", lang)
+			w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			w.WriteHeader(localized.StatusCode)
+			if err := json.NewEncoder(w).Encode(&localized); err != nil {
+				logging.Logf(logrus.ErrorLevel, "Error occurred in HTTPS redirect handler: %v", err)
+			}
+			return
+		}
+
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			// IPv6 addresses must be enclosed in brackets
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/mail"
+	"github.com/IV1201-Group-2/login-service/metrics"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	echojwt "github.com/labstack/echo-jwt/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Number of users returned per page if the caller doesn't specify a limit.
+const defaultUserLimit = 50
+
+type userListParams struct {
+	Search string      `query:"search" validate:"omitempty,max=255"`
+	Role   *model.Role `query:"role"   validate:"omitempty,oneof=1 2"`
+
+	Limit  uint64 `query:"limit"  validate:"omitempty,min=1,max=200"`
+	Offset uint64 `query:"offset"`
+}
+
+// User list route handler.
+// Only recruiters are allowed to list and search users.
+func ListUsers(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, lockout *lockout) error {
+	claims, err := requireRole(c, model.RoleRecruiter)
+	if err != nil {
+		return err
+	}
+
+	var params userListParams
+	// Check that all parameters are valid
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultUserLimit
+	}
+
+	users, total, err := userRepository.List(c.Request().Context(), database.UserFilter{
+		Search: params.Search,
+		Role:   params.Role,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	})
+	if err != nil {
+		return err
+	}
+	if err := setLocked(c, auditRepository, lockout, users); err != nil {
+		return err
+	}
+	logging.Logcf(logrus.InfoLevel, c, "Recruiter %d listed %d users", claims.User.ID, len(users))
+
+	return c.JSON(http.StatusOK, model.UserPage{
+		Users:  users,
+		Total:  total,
+		Limit:  int(params.Limit),
+		Offset: int(params.Offset),
+	})
+}
+
+type userParams struct {
+	ID int `param:"id" json:"-" form:"-" validate:"min=0"`
+}
+
+// Sets whether the users are locked out after too many wrong passwords.
+func setLocked(c echo.Context, auditRepository *database.AuditRepository, lockout *lockout, users []model.UserStatus) error {
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	locked, err := lockout.lockedUntil(c, auditRepository, "", ids...)
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if until, ok := locked[users[i].ID]; ok {
+			users[i].Locked = true
+			users[i].LockedUntil = &until
+		}
+	}
+	return nil
+}
+
+// Returns the status of the user identified by the route, or ErrUserNotFound.
+func userStatus(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, lockout *lockout, id int) (*model.UserStatus, error) {
+	status, err := userRepository.Status(c.Request().Context(), id)
+	if errors.Is(err, database.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	users := []model.UserStatus{*status}
+	if err := setLocked(c, auditRepository, lockout, users); err != nil {
+		return nil, err
+	}
+	return &users[0], nil
+}
+
+// User status route handler.
+// Only recruiters are allowed to view the status of a user.
+func GetUser(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, lockout *lockout) error {
+	claims, err := requireRole(c, model.RoleRecruiter)
+	if err != nil {
+		return err
+	}
+
+	var params userParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	status, err := userStatus(c, userRepository, auditRepository, lockout, params.ID)
+	if err != nil {
+		return err
+	}
+	logging.Logcf(logrus.InfoLevel, c, "Recruiter %d viewed user %d", claims.User.ID, params.ID)
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// User reset route handler.
+// Recruiters can log a user out everywhere and mail them a link that lets them choose a new password.
+// The link is only sent to the user's own address, so that recruiters can't take over the accounts they reset.
+// The reset token is recorded in the audit trail of the user.
+func ResetUser(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, sessionRepository *database.SessionRepository, lockout *lockout, mailer mail.Mailer, auth *echojwt.Config, resetURL string) error {
+	if mailer == nil {
+		return ErrFeatureDisabled
+	}
+	claims, err := requireRole(c, model.RoleRecruiter)
+	if err != nil {
+		return err
+	}
+
+	var params userParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	status, err := userStatus(c, userRepository, auditRepository, lockout, params.ID)
+	if err != nil {
+		return err
+	}
+	// Disabled users must not be able to choose a new password
+	if status.Disabled {
+		logging.Logcf(logrus.WarnLevel, c, "Recruiter %d tried to reset disabled user %d", claims.User.ID, params.ID)
+		return ErrAccountDisabled
+	}
+	if status.Email == "" {
+		logging.Logcf(logrus.WarnLevel, c, "Recruiter %d tried to reset user %d, who has no e-mail address", claims.User.ID, params.ID)
+		return ErrMissingEmail
+	}
+
+	// Whoever is using the account must not stay logged in while the user chooses a new password
+	if err := sessionRepository.RevokeAll(ctx, params.ID); err != nil {
+		return err
+	}
+	if err := service.SendPasswordReset(ctx, mailer, status.User, auth.SigningKey, resetURL); err != nil {
+		return err
+	}
+	recordUserEvent(c, auditRepository, status.User, model.AuthEventResetTokenIssued, metrics.OutcomeSuccess)
+	logging.Logcf(logrus.InfoLevel, c, "Recruiter %d logged out user %d and mailed them a reset link", claims.User.ID, params.ID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+type roleParams struct {
+	ID   int        `param:"id" json:"-" form:"-" validate:"min=0"`
+	Role model.Role `form:"role" json:"role" validate:"required,oneof=1 2"`
+}
+
+// User role route handler.
+// Recruiters can change the role of other users, but not their own.
+func UpdateUserRole(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, lockout *lockout) error {
+	claims, err := requireRole(c, model.RoleRecruiter)
+	if err != nil {
+		return err
+	}
+
+	var params roleParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	// Recruiters would be able to lock everyone out of user management
+	if params.ID == claims.User.ID {
+		logging.Logcf(logrus.WarnLevel, c, "Recruiter %d tried to change their own role", claims.User.ID)
+		return ErrForbidden
+	}
+
+	ctx := c.Request().Context()
+	err = userRepository.UpdateRole(ctx, params.ID, params.Role)
+	if errors.Is(err, database.ErrUserNotFound) {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+	logging.Logcf(logrus.InfoLevel, c, "Recruiter %d changed the role of user %d to %d", claims.User.ID, params.ID, params.Role)
+
+	status, err := userStatus(c, userRepository, auditRepository, lockout, params.ID)
+	if err != nil {
+		return err
+	}
+	recordUserEvent(c, auditRepository, status.User, model.AuthEventRoleChange, metrics.OutcomeSuccess)
+	return c.JSON(http.StatusOK, status)
+}
+
+// User disable and enable route handler.
+// Recruiters can disable the accounts of other users, which prevents them from logging in.
+// Tokens that have already been issued to a disabled user stop working.
+func SetUserDisabled(c echo.Context, userRepository *database.UserRepository, auditRepository *database.AuditRepository, lockout *lockout, disabled bool) error {
+	claims, err := requireRole(c, model.RoleRecruiter)
+	if err != nil {
+		return err
+	}
+
+	var params userParams
+	if err := bindParams(c, &params); err != nil {
+		return err
+	}
+	// Recruiters would be able to lock everyone out of user management
+	if params.ID == claims.User.ID {
+		logging.Logcf(logrus.WarnLevel, c, "Recruiter %d tried to change the status of their own account", claims.User.ID)
+		return ErrForbidden
+	}
+
+	ctx := c.Request().Context()
+	err = userRepository.SetDisabled(ctx, params.ID, disabled)
+	if errors.Is(err, database.ErrUserNotFound) {
+		return ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+	eventType := model.AuthEventAccountEnabled
+	if disabled {
+		eventType = model.AuthEventAccountDisabled
+		logging.Logcf(logrus.InfoLevel, c, "Recruiter %d disabled user %d", claims.User.ID, params.ID)
+	} else {
+		logging.Logcf(logrus.InfoLevel, c, "Recruiter %d enabled user %d", claims.User.ID, params.ID)
+	}
+
+	status, err := userStatus(c, userRepository, auditRepository, lockout, params.ID)
+	if err != nil {
+		return err
+	}
+	recordUserEvent(c, auditRepository, status.User, eventType, metrics.OutcomeSuccess)
+	return c.JSON(http.StatusOK, status)
+}
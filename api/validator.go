@@ -1,7 +1,18 @@
 package api
 
 import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
 	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
 // Custom validator that uses go-playground/validator.
@@ -11,14 +22,91 @@ type Validator struct {
 
 // NewValidator creates a new instance of service.Validator.
 func NewValidator() *Validator {
-	return &Validator{validator: validator.New(validator.WithRequiredStructEnabled())}
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by the name that users send them as
+	v.RegisterTagNameFunc(parameterName)
+	_ = v.RegisterValidation(personalNumberRule, validPersonalNumber)
+	return &Validator{validator: v}
+}
+
+// Rule of a Swedish personal number with a valid checksum.
+const personalNumberRule = "personal_number"
+
+func validPersonalNumber(fl validator.FieldLevel) bool {
+	_, ok := service.NormalizePersonalNumber(fl.Field().String())
+	return ok
+}
+
+// Returns the name of a parameter in JSON, forms, query strings or the path.
+func parameterName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "param"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
 }
 
 // Validates user data using go-playground/validator.
+// Returns ErrMissingParameters with a model.FieldError for each invalid field.
 func (cv *Validator) Validate(i any) error {
-	if err := cv.validator.Struct(i); err != nil {
+	err := cv.validator.Struct(i)
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]model.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, model.FieldError{Field: fieldErr.Field(), Rule: fieldErr.Tag(), Param: fieldErr.Param()})
+		}
+		return ErrMissingParameters.WithDetails(fields)
+	} else if err != nil {
 		return ErrMissingParameters.Wrap(err)
 	}
 
 	return nil
 }
+
+// Binds request parameters to params and validates them.
+// Returns ErrMalformedRequest if the request body can't be parsed,
+// or ErrMissingParameters with the invalid fields if a parameter is missing, invalid or has the wrong JSON type.
+func bindParams(c echo.Context, params any) error {
+	if err := c.Bind(params); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		// The body itself has the wrong type if no field is named
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return ErrMissingParameters.WithDetails([]model.FieldError{
+				{Field: typeErr.Field, Rule: model.FieldRuleType, Param: jsonType(typeErr.Type)},
+			})
+		}
+
+		// Syntax errors, unsupported content types and values in forms or query strings that can't be converted
+		logging.Logcf(logrus.InfoLevel, c, "Rejected malformed request: %v", err)
+		return ErrMalformedRequest
+	}
+	return c.Validate(params)
+}
+
+// Returns the name of the JSON type that a Go type is decoded from.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types such as time.Time are decoded from strings
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
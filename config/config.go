@@ -0,0 +1,473 @@
+// The package config loads the configuration of the service from an optional YAML file
+// and environment variables, and validates it before the service starts.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/tracing"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable that specifies the path to the YAML configuration file.
+const FileEnv = "CONFIG_FILE"
+
+// Secrets can also be read from the file specified by the environment variable with this suffix,
+// for example JWT_SECRET_FILE instead of JWT_SECRET.
+const secretFileSuffix = "_FILE"
+
+// ErrInvalid indicates that a configuration value is missing or invalid.
+var ErrInvalid = errors.New("invalid configuration")
+
+// Config is the complete configuration of the service.
+type Config struct {
+	// Port that the HTTP server listens on.
+	Port string `yaml:"port"`
+	// How long in-flight requests are given to finish after SIGTERM or SIGINT.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// Port that Prometheus metrics are served on over plain HTTP.
+	// If empty, metrics are not served.
+	MetricsPort string `yaml:"metrics_port"`
+
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	Log      Log      `yaml:"log"`
+	Tracing  Tracing  `yaml:"tracing"`
+	TLS      TLS      `yaml:"tls"`
+	CORS     CORS     `yaml:"cors"`
+	Mail     Mail     `yaml:"mail"`
+
+	// Files that secrets were read from. Watched by the server to reload secrets when they change.
+	SecretFiles []string `yaml:"-"`
+}
+
+// Database configures the database connection pool.
+type Database struct {
+	// Connection string for the database.
+	URL string `yaml:"url"`
+	// Maximum number of open connections, 0 means unlimited.
+	MaxConnections int `yaml:"max_connections"`
+	// Maximum number of idle connections kept in the pool.
+	MaxIdleConnections int `yaml:"max_idle_connections"`
+	// Number of times to retry the initial ping.
+	ConnectRetries int `yaml:"connect_retries"`
+}
+
+// Auth configures how tokens are signed and passwords are hashed.
+type Auth struct {
+	// Key used to sign JWT tokens.
+	JWTSecret string `yaml:"jwt_secret"`
+	// How long login tokens are valid.
+	TokenExpiry time.Duration `yaml:"token_expiry"`
+	// How long password reset tokens are valid.
+	ResetTokenExpiry time.Duration `yaml:"reset_token_expiry"`
+	// How long e-mail verification links are valid.
+	VerifyTokenExpiry time.Duration `yaml:"verify_token_expiry"`
+	// The bcrypt cost used when hashing new passwords.
+	PasswordCost int `yaml:"password_cost"`
+	// If set, login tokens are also sent in an HttpOnly cookie, which is accepted instead of the Authorization header.
+	TokenCookie bool `yaml:"token_cookie"`
+	// SameSite attribute of the token cookie: "strict", "lax" or "none".
+	TokenCookieSameSite string `yaml:"token_cookie_same_site"`
+	// Number of wrong passwords within the lockout period after which the account is locked, 0 disables lockout.
+	MaxFailedAttempts int `yaml:"max_failed_attempts"`
+	// How long wrong passwords count towards the lockout.
+	LockoutPeriod time.Duration `yaml:"lockout_period"`
+	// If set, applicants can register themselves and verify their e-mail address. Requires mail.
+	Registration bool `yaml:"registration"`
+}
+
+// Values of the SameSite attribute of the token cookie.
+const (
+	SameSiteStrict = "strict"
+	SameSiteLax    = "lax"
+	SameSiteNone   = "none"
+)
+
+// Log configures the format and destination of logs.
+type Log struct {
+	Level        string `yaml:"level"`
+	Format       string `yaml:"format"`
+	Redaction    string `yaml:"redaction"`
+	RedactionKey string `yaml:"redaction_key"`
+	// File that logs are appended to instead of stdout.
+	File string `yaml:"file"`
+	// Size in megabytes at which the log file is rotated, 0 disables size-based rotation.
+	FileMaxSize int64 `yaml:"file_max_size"`
+	// Age at which the log file is rotated, 0 disables age-based rotation.
+	FileMaxAge time.Duration `yaml:"file_max_age"`
+	// Number of compressed backups to keep, 0 keeps all backups.
+	FileMaxBackups int `yaml:"file_max_backups"`
+}
+
+// Tracing configures where spans are exported.
+type Tracing struct {
+	Exporter string `yaml:"exporter"`
+}
+
+// TLS configures HTTPS. The server uses plain HTTP if no certificate is set.
+type TLS struct {
+	// PEM-encoded certificate chain and private key. Reloaded when the files change.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// Port of an optional plain HTTP listener that redirects all requests to HTTPS.
+	RedirectPort string `yaml:"redirect_port"`
+}
+
+// Enabled returns true if the server should use HTTPS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// CORS configures which browser origins can call the API.
+type CORS struct {
+	// Origins that are allowed, such as "https://example.com" or "https://*.example.com" for all subdomains.
+	// A single "*" allows any origin. If empty, cross-origin requests are not allowed.
+	AllowOrigins []string `yaml:"allow_origins"`
+	// Methods and headers that cross-origin requests can use.
+	AllowMethods []string `yaml:"allow_methods"`
+	AllowHeaders []string `yaml:"allow_headers"`
+	// If set, browsers send cookies and credentials with cross-origin requests.
+	AllowCredentials bool `yaml:"allow_credentials"`
+	// How long browsers can cache the result of a preflight request.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+// Mail configures how e-mail is sent to users.
+// Mail is optional, but routes that mail links to users are disabled without it.
+type Mail struct {
+	// Address of the SMTP server, such as "smtp.example.com:587".
+	SMTPAddr     string `yaml:"smtp_addr"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	// If set, e-mail is written to the log instead of being sent through SMTP.
+	// This is only suitable for development, since the log then contains links that grant access to accounts.
+	Log bool `yaml:"log"`
+	// Sender address of all e-mail.
+	From string `yaml:"from"`
+	// Page that verifies e-mail addresses, which receives the token as the "token" query parameter.
+	// Required when e-mail is sent through SMTP. With log mail, links point to the verification route of the service on localhost by default.
+	VerifyURL string `yaml:"verify_url"`
+	// Page that lets users choose a new password, which receives the reset token as the "token" query parameter.
+	// Required when e-mail is sent through SMTP. With log mail, links point to the reset route of the service on localhost by default.
+	ResetURL string `yaml:"reset_url"`
+}
+
+// Enabled returns true if e-mail can be sent, either through SMTP or to the log.
+func (m Mail) Enabled() bool {
+	return m.SMTPAddr != "" || m.Log
+}
+
+// Default returns the configuration used for values that are not set in the file or environment.
+func Default() *Config {
+	return &Config{
+		ShutdownTimeout: 10 * time.Second,
+		Database: Database{
+			MaxIdleConnections: 1,
+			ConnectRetries:     5,
+		},
+		Auth: Auth{
+			TokenExpiry:       time.Hour,
+			ResetTokenExpiry:  10 * time.Minute,
+			VerifyTokenExpiry: 24 * time.Hour,
+			// A value of 10 matches the cost of the default Spring BCryptPasswordEncoder.
+			PasswordCost:        10,
+			TokenCookieSameSite: SameSiteStrict,
+			MaxFailedAttempts:   5,
+			LockoutPeriod:       15 * time.Minute,
+		},
+		Log: Log{
+			Level:          logrus.InfoLevel.String(),
+			Format:         logging.FormatText,
+			Redaction:      logging.RedactOff,
+			FileMaxSize:    100,
+			FileMaxAge:     24 * time.Hour,
+			FileMaxBackups: 5,
+		},
+		Tracing: Tracing{
+			Exporter: tracing.ExporterNone,
+		},
+		CORS: CORS{
+			AllowMethods: []string{http.MethodGet, http.MethodPost},
+			AllowHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+			MaxAge:       10 * time.Minute,
+		},
+		Mail: Mail{
+			From: "no-reply@localhost",
+		},
+	}
+}
+
+// Load reads the configuration from the file specified by CONFIG_FILE, if any,
+// overrides it with environment variables and validates the result.
+func Load() (*Config, error) {
+	config := Default()
+
+	if path, ok := os.LookupEnv(FileEnv); ok && path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Reads values from a YAML file. Keys that are not present keep their current value.
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(content, c); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalid, path, err)
+	}
+	return nil
+}
+
+// Environment variables that contain secrets and can be replaced by a *_FILE variant.
+var secretEnvVars = []string{"DATABASE_URL", "JWT_SECRET", "LOG_REDACTION_KEY", "MAIL_SMTP_PASSWORD"}
+
+// Environment variables that override values in the configuration file.
+func (c *Config) envVars() []struct {
+	name   string
+	target any
+} {
+	return []struct {
+		name   string
+		target any
+	}{
+		{"PORT", &c.Port},
+		{"SHUTDOWN_TIMEOUT", &c.ShutdownTimeout},
+		{"METRICS_PORT", &c.MetricsPort},
+		{"DATABASE_URL", &c.Database.URL},
+		{"DATABASE_MAX_CONNECTIONS", &c.Database.MaxConnections},
+		{"DATABASE_MAX_IDLE_CONNECTIONS", &c.Database.MaxIdleConnections},
+		{"DATABASE_CONNECT_RETRIES", &c.Database.ConnectRetries},
+		{"JWT_SECRET", &c.Auth.JWTSecret},
+		{"TOKEN_EXPIRY", &c.Auth.TokenExpiry},
+		{"RESET_TOKEN_EXPIRY", &c.Auth.ResetTokenExpiry},
+		{"VERIFY_TOKEN_EXPIRY", &c.Auth.VerifyTokenExpiry},
+		{"PASSWORD_COST", &c.Auth.PasswordCost},
+		{"TOKEN_COOKIE", &c.Auth.TokenCookie},
+		{"REGISTRATION", &c.Auth.Registration},
+		{"TOKEN_COOKIE_SAME_SITE", &c.Auth.TokenCookieSameSite},
+		{"MAX_FAILED_ATTEMPTS", &c.Auth.MaxFailedAttempts},
+		{"LOCKOUT_PERIOD", &c.Auth.LockoutPeriod},
+		{"LOG_LEVEL", &c.Log.Level},
+		{"LOG_FORMAT", &c.Log.Format},
+		{"LOG_REDACTION", &c.Log.Redaction},
+		{"LOG_REDACTION_KEY", &c.Log.RedactionKey},
+		{"LOG_FILE", &c.Log.File},
+		{"LOG_FILE_MAX_SIZE", &c.Log.FileMaxSize},
+		{"LOG_FILE_MAX_AGE", &c.Log.FileMaxAge},
+		{"LOG_FILE_MAX_BACKUPS", &c.Log.FileMaxBackups},
+		{"TRACING_EXPORTER", &c.Tracing.Exporter},
+		{"TLS_CERT_FILE", &c.TLS.CertFile},
+		{"TLS_KEY_FILE", &c.TLS.KeyFile},
+		{"TLS_REDIRECT_PORT", &c.TLS.RedirectPort},
+		{"CORS_ALLOW_ORIGINS", &c.CORS.AllowOrigins},
+		{"CORS_ALLOW_METHODS", &c.CORS.AllowMethods},
+		{"CORS_ALLOW_HEADERS", &c.CORS.AllowHeaders},
+		{"CORS_ALLOW_CREDENTIALS", &c.CORS.AllowCredentials},
+		{"CORS_MAX_AGE", &c.CORS.MaxAge},
+		{"MAIL_SMTP_ADDR", &c.Mail.SMTPAddr},
+		{"MAIL_SMTP_USERNAME", &c.Mail.SMTPUsername},
+		{"MAIL_SMTP_PASSWORD", &c.Mail.SMTPPassword},
+		{"MAIL_LOG", &c.Mail.Log},
+		{"MAIL_FROM", &c.Mail.From},
+		{"MAIL_VERIFY_URL", &c.Mail.VerifyURL},
+		{"MAIL_RESET_URL", &c.Mail.ResetURL},
+	}
+}
+
+// Looks up a secret from the file specified by NAME_FILE, or from NAME if no file is specified.
+func (c *Config) lookupSecret(name string) (string, bool, error) {
+	path, ok := os.LookupEnv(name + secretFileSuffix)
+	if !ok {
+		value, ok := os.LookupEnv(name)
+		return value, ok, nil
+	}
+	if _, ok := os.LookupEnv(name); ok {
+		return "", false, fmt.Errorf("%w: only one of $%s and $%s%s can be set", ErrInvalid, name, name, secretFileSuffix)
+	}
+
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return "", false, fmt.Errorf("read $%s%s: %w", name, secretFileSuffix, err)
+	}
+	c.SecretFiles = append(c.SecretFiles, path)
+	// Files created with echo or editors usually end with a newline
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// Overrides values with environment variables that are set.
+func (c *Config) loadEnv() error {
+	var errs []error
+	for _, env := range c.envVars() {
+		value, ok := os.LookupEnv(env.name)
+		if slices.Contains(secretEnvVars, env.name) {
+			var err error
+			if value, ok, err = c.lookupSecret(env.name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		var err error
+		var kind string
+		switch target := env.target.(type) {
+		case *string:
+			*target = value
+		case *int:
+			*target, err = strconv.Atoi(value)
+			kind = "an integer"
+		case *int64:
+			*target, err = strconv.ParseInt(value, 10, 64)
+			kind = "an integer"
+		case *time.Duration:
+			*target, err = time.ParseDuration(value)
+			kind = "a duration"
+		case *bool:
+			*target, err = strconv.ParseBool(value)
+			kind = "a boolean"
+		case *[]string:
+			// Lists are comma-separated
+			*target = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*target = append(*target, item)
+				}
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: $%s %q is not %s", ErrInvalid, env.name, value, kind))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that all required values are set and that all values are within range.
+// Every problem is reported, not only the first one.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalid}, args...)...))
+		}
+	}
+
+	check(c.Port != "", "port ($PORT) must be set")
+	check(c.Port == "" || validPort(c.Port), "port ($PORT) %q is not a valid port number", c.Port)
+	check(c.ShutdownTimeout >= 0, "shutdown_timeout ($SHUTDOWN_TIMEOUT) must not be negative")
+	check(c.MetricsPort == "" || validPort(c.MetricsPort), "metrics_port ($METRICS_PORT) %q is not a valid port number", c.MetricsPort)
+	check(c.MetricsPort == "" || (c.MetricsPort != c.Port && c.MetricsPort != c.TLS.RedirectPort),
+		"metrics_port ($METRICS_PORT) must be different from port ($PORT) and tls.redirect_port ($TLS_REDIRECT_PORT)")
+
+	check(c.Database.URL != "", "database.url ($DATABASE_URL) must be set")
+	check(c.Database.MaxConnections >= 0, "database.max_connections ($DATABASE_MAX_CONNECTIONS) must not be negative")
+	check(c.Database.MaxIdleConnections >= 0, "database.max_idle_connections ($DATABASE_MAX_IDLE_CONNECTIONS) must not be negative")
+	check(c.Database.MaxConnections == 0 || c.Database.MaxIdleConnections <= c.Database.MaxConnections,
+		"database.max_idle_connections ($DATABASE_MAX_IDLE_CONNECTIONS) must not be greater than database.max_connections")
+	check(c.Database.ConnectRetries >= 0, "database.connect_retries ($DATABASE_CONNECT_RETRIES) must not be negative")
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret ($JWT_SECRET) must be set")
+	check(c.Auth.TokenExpiry > 0, "auth.token_expiry ($TOKEN_EXPIRY) must be positive")
+	check(c.Auth.ResetTokenExpiry > 0, "auth.reset_token_expiry ($RESET_TOKEN_EXPIRY) must be positive")
+	check(c.Auth.VerifyTokenExpiry > 0, "auth.verify_token_expiry ($VERIFY_TOKEN_EXPIRY) must be positive")
+	check(c.Auth.PasswordCost >= bcrypt.MinCost && c.Auth.PasswordCost <= bcrypt.MaxCost,
+		"auth.password_cost ($PASSWORD_COST) must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	check(slices.Contains([]string{SameSiteStrict, SameSiteLax, SameSiteNone}, c.Auth.TokenCookieSameSite),
+		"auth.token_cookie_same_site ($TOKEN_COOKIE_SAME_SITE) %q must be %q, %q or %q", c.Auth.TokenCookieSameSite, SameSiteStrict, SameSiteLax, SameSiteNone)
+	check(c.Auth.MaxFailedAttempts >= 0, "auth.max_failed_attempts ($MAX_FAILED_ATTEMPTS) must not be negative")
+	check(c.Auth.LockoutPeriod > 0, "auth.lockout_period ($LOCKOUT_PERIOD) must be positive")
+
+	_, err := logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level ($LOG_LEVEL) %q is not a known log level", c.Log.Level)
+	check(slices.Contains([]string{logging.FormatText, logging.FormatJSON}, c.Log.Format),
+		"log.format ($LOG_FORMAT) %q must be %q or %q", c.Log.Format, logging.FormatText, logging.FormatJSON)
+	check(slices.Contains([]string{logging.RedactOff, logging.RedactMask, logging.RedactHash}, c.Log.Redaction),
+		"log.redaction ($LOG_REDACTION) %q must be %q, %q or %q", c.Log.Redaction, logging.RedactOff, logging.RedactMask, logging.RedactHash)
+	check(c.Log.FileMaxSize >= 0, "log.file_max_size ($LOG_FILE_MAX_SIZE) must not be negative")
+	check(c.Log.FileMaxAge >= 0, "log.file_max_age ($LOG_FILE_MAX_AGE) must not be negative")
+	check(c.Log.FileMaxBackups >= 0, "log.file_max_backups ($LOG_FILE_MAX_BACKUPS) must not be negative")
+
+	check(slices.Contains([]string{tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout}, c.Tracing.Exporter),
+		"tracing.exporter ($TRACING_EXPORTER) %q must be %q, %q or %q", c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout)
+
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls.cert_file ($TLS_CERT_FILE) and tls.key_file ($TLS_KEY_FILE) must be set together")
+	check(c.TLS.RedirectPort == "" || c.TLS.Enabled(), "tls.redirect_port ($TLS_REDIRECT_PORT) requires tls.cert_file ($TLS_CERT_FILE)")
+	check(c.TLS.RedirectPort == "" || validPort(c.TLS.RedirectPort), "tls.redirect_port ($TLS_REDIRECT_PORT) %q is not a valid port number", c.TLS.RedirectPort)
+	check(c.TLS.RedirectPort == "" || c.TLS.RedirectPort != c.Port, "tls.redirect_port ($TLS_REDIRECT_PORT) must be different from port ($PORT)")
+
+	for _, origin := range c.CORS.AllowOrigins {
+		check(validOrigin(origin), "cors.allow_origins ($CORS_ALLOW_ORIGINS) %q is not an origin such as \"https://example.com\" or \"https://*.example.com\"", origin)
+	}
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowOrigins, "*"),
+		"cors.allow_credentials ($CORS_ALLOW_CREDENTIALS) can't be used when any origin is allowed")
+	check(c.CORS.MaxAge >= 0, "cors.max_age ($CORS_MAX_AGE) must not be negative")
+
+	_, err = mail.ParseAddress(c.Mail.From)
+	check(err == nil, "mail.from ($MAIL_FROM) %q is not an e-mail address", c.Mail.From)
+	check(!c.Auth.Registration || c.Mail.Enabled(),
+		"auth.registration ($REGISTRATION) requires mail.smtp_addr ($MAIL_SMTP_ADDR), or mail.log ($MAIL_LOG) to write e-mail to the log")
+	check(c.Mail.SMTPAddr == "" || !c.Mail.Log, "mail.smtp_addr ($MAIL_SMTP_ADDR) and mail.log ($MAIL_LOG) can't both be set")
+	check(c.Mail.SMTPAddr == "" || validHostPort(c.Mail.SMTPAddr), "mail.smtp_addr ($MAIL_SMTP_ADDR) %q must be a host and port", c.Mail.SMTPAddr)
+	check((c.Mail.SMTPUsername == "") == (c.Mail.SMTPPassword == ""),
+		"mail.smtp_username ($MAIL_SMTP_USERNAME) and mail.smtp_password ($MAIL_SMTP_PASSWORD) must be set together")
+	check(c.Mail.VerifyURL == "" || validURL(c.Mail.VerifyURL), "mail.verify_url ($MAIL_VERIFY_URL) %q is not an http or https URL", c.Mail.VerifyURL)
+	check(c.Mail.ResetURL == "" || validURL(c.Mail.ResetURL), "mail.reset_url ($MAIL_RESET_URL) %q is not an http or https URL", c.Mail.ResetURL)
+	// Links to localhost would be mailed to real users
+	check(c.Mail.SMTPAddr == "" || c.Mail.VerifyURL != "", "mail.verify_url ($MAIL_VERIFY_URL) must be set when mail.smtp_addr ($MAIL_SMTP_ADDR) is set")
+	check(c.Mail.SMTPAddr == "" || c.Mail.ResetURL != "", "mail.reset_url ($MAIL_RESET_URL) must be set when mail.smtp_addr ($MAIL_SMTP_ADDR) is set")
+
+	return errors.Join(errs...)
+}
+
+// Returns true if origin is "*" or a scheme and host, where the host can start with "*." to match subdomains.
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	parsed, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" &&
+		parsed.Path == "" && parsed.RawQuery == "" && parsed.Fragment == "" && parsed.User == nil && !strings.Contains(parsed.Host, "*")
+}
+
+// Returns true if address is a host followed by a valid port number.
+func validHostPort(address string) bool {
+	host, port, err := net.SplitHostPort(address)
+	return err == nil && host != "" && validPort(port)
+}
+
+// Returns true if rawURL is an absolute http or https URL.
+func validURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// Returns true if port is a number between 0 and 65535.
+func validPort(port string) bool {
+	number, err := strconv.Atoi(port)
+	return err == nil && number >= 0 && number <= 65535
+}
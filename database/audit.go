@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/model"
+	sq "github.com/Masterminds/squirrel"
+)
+
+// AuditFilter restricts which events are returned from the audit trail.
+// Zero values match every event.
+type AuditFilter struct {
+	PersonID *int
+	Identity string
+	Type     string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+
+	Limit  uint64
+	Offset uint64
+}
+
+type AuditRepository struct {
+	conn    *sql.DB
+	breaker *Breaker
+}
+
+// NewAuditRepository creates a new repository from a database connection.
+func NewAuditRepository(conn *sql.DB) *AuditRepository {
+	return &AuditRepository{conn, breakerFor(conn)}
+}
+
+// Insert an event into the audit trail.
+func (a *AuditRepository) Insert(ctx context.Context, event model.AuthEvent) error {
+	tx, err := begin(ctx, a.conn, a.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Insert("auth_event").
+		Columns("event_type", "person_id", "identity", "ip", "user_agent", "outcome", "created_at").
+		Values(event.Type, event.PersonID, event.Identity, event.IP, event.UserAgent, event.Outcome, event.CreatedAt)
+
+	ctx, span := startStatement(ctx, "INSERT", "auth_event", query)
+	_, err = query.ExecContext(ctx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+func (f AuditFilter) where() sq.And {
+	where := sq.And{}
+	if f.PersonID != nil {
+		where = append(where, sq.Eq{"person_id": *f.PersonID})
+	}
+	if f.Identity != "" {
+		where = append(where, sq.Eq{"identity": f.Identity})
+	}
+	if f.Type != "" {
+		where = append(where, sq.Eq{"event_type": f.Type})
+	}
+	if f.Outcome != "" {
+		where = append(where, sq.Eq{"outcome": f.Outcome})
+	}
+	if f.From != nil {
+		where = append(where, sq.GtOrEq{"created_at": *f.From})
+	}
+	if f.To != nil {
+		where = append(where, sq.Lt{"created_at": *f.To})
+	}
+	return where
+}
+
+// List events matching the filter, newest first.
+// This function also returns the total number of matching events for pagination.
+func (a *AuditRepository) List(ctx context.Context, filter AuditFilter) ([]model.AuthEvent, int, error) {
+	// Begin transaction:
+	// The count and the page need to be read from the same snapshot.
+	tx, err := begin(ctx, a.conn, a.breaker, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	var total int
+	countQuery := stmtBuilder.RunWith(tx).
+		Select("count(*)").
+		From("auth_event").
+		Where(filter.where())
+
+	countCtx, span := startStatement(ctx, "SELECT", "auth_event", countQuery)
+	err = countQuery.ScanContext(countCtx, &total)
+	tx.endStatement(span, err)
+	if err != nil {
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+
+	query := stmtBuilder.RunWith(tx).
+		Select("auth_event_id", "event_type", "person_id", "identity", "ip", "user_agent", "outcome", "created_at").
+		From("auth_event").
+		Where(filter.where()).
+		OrderBy("created_at DESC", "auth_event_id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset)
+
+	queryCtx, span := startStatement(ctx, "SELECT", "auth_event", query)
+	rows, err := query.QueryContext(queryCtx)
+	if err != nil {
+		tx.endStatement(span, err)
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+	defer rows.Close()
+
+	events := []model.AuthEvent{}
+	for rows.Next() {
+		var event model.AuthEvent
+		var personID sql.NullInt64
+		var identity, ip, userAgent sql.NullString
+
+		if err = rows.Scan(&event.ID, &event.Type, &personID, &identity, &ip, &userAgent, &event.Outcome, &event.CreatedAt); err != nil {
+			tx.endStatement(span, err)
+			return nil, 0, ErrQueryFailed.Wrap(err)
+		}
+
+		if personID.Valid {
+			id := int(personID.Int64)
+			event.PersonID = &id
+		}
+		event.Identity = identity.String
+		event.IP = ip.String
+		event.UserAgent = userAgent.String
+
+		events = append(events, event)
+	}
+	err = rows.Err()
+	tx.endStatement(span, err)
+	if err != nil {
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+
+	return events, total, nil
+}
+
+// NthNewest returns when each of the users had the nth newest event with the outcome since a point in time.
+// Events are counted separately for each IP address. If ip is empty, the latest of the addresses is returned,
+// otherwise only events from ip are counted. Users with fewer than n such events from an address are left out.
+func (a *AuditRepository) NthNewest(ctx context.Context, personIDs []int, ip string, outcome string, since time.Time, n int) (map[int]time.Time, error) {
+	tx, err := begin(ctx, a.conn, a.breaker, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	ranked := stmtBuilder.
+		Select("person_id", "created_at").
+		Column("row_number() OVER (PARTITION BY person_id, ip ORDER BY created_at DESC) AS n").
+		From("auth_event").
+		Where(sq.Eq{"person_id": personIDs, "outcome": outcome}).
+		Where(sq.GtOrEq{"created_at": since})
+	if ip != "" {
+		ranked = ranked.Where(sq.Eq{"ip": ip})
+	}
+	query := stmtBuilder.RunWith(tx).
+		Select("person_id", "created_at").
+		FromSelect(ranked, "ranked").
+		Where(sq.Eq{"n": n})
+
+	queryCtx, span := startStatement(ctx, "SELECT", "auth_event", query)
+	rows, err := query.QueryContext(queryCtx)
+	if err != nil {
+		tx.endStatement(span, err)
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+	defer rows.Close()
+
+	result := map[int]time.Time{}
+	for rows.Next() {
+		var personID int
+		var createdAt time.Time
+		if err = rows.Scan(&personID, &createdAt); err != nil {
+			tx.endStatement(span, err)
+			return nil, ErrQueryFailed.Wrap(err)
+		}
+		if createdAt.After(result[personID]) {
+			result[personID] = createdAt
+		}
+	}
+	err = rows.Err()
+	tx.endStatement(span, err)
+	if err != nil {
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+
+	return result, nil
+}
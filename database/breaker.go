@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BreakerThreshold is the number of consecutive connection failures that opens the circuit.
+const BreakerThreshold = 5
+
+// While the circuit is open, the database is pinged in the background to check if it has recovered.
+// The interval starts at breakerProbeInitial and doubles after every failed ping.
+const (
+	breakerProbeInitial = time.Second
+	breakerProbeMax     = 30 * time.Second
+	breakerProbeTimeout = 5 * time.Second
+)
+
+// retryAfterError is wrapped by ErrCircuitOpen to tell the caller when to try again.
+type retryAfterError struct {
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return "retry after " + e.after.String()
+}
+
+// RetryAfter returns how long the caller should wait before retrying
+// if the error was caused by an open circuit breaker.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryErr *retryAfterError
+	if errors.As(err, &retryErr) {
+		return retryErr.after, true
+	}
+	return 0, false
+}
+
+// Breaker is a circuit breaker that protects the database from requests while it is unavailable.
+// When the circuit is open, calls fail fast with ErrCircuitOpen and the database is probed
+// in the background until it recovers.
+// Open creates one breaker for each connection pool, which is shared by all repositories that use the pool.
+type Breaker struct {
+	mu       sync.Mutex
+	failures int
+	open     bool
+	retryAt  time.Time
+	probing  bool
+
+	probe func(ctx context.Context) error
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// NewBreaker creates a closed circuit breaker that uses probe to check if the database has recovered.
+func NewBreaker(probe func(ctx context.Context) error) *Breaker {
+	return &Breaker{probe: probe, stop: make(chan struct{})}
+}
+
+// Allow returns ErrCircuitOpen if the circuit is open, otherwise nil.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	after := time.Until(b.retryAt)
+	if after < time.Second {
+		after = time.Second
+	}
+	return ErrCircuitOpen.Wrap(&retryAfterError{after})
+}
+
+// Record the result of a call to the database.
+// Once BreakerThreshold consecutive calls have failed to reach the database the circuit opens.
+// Other errors, such as constraint violations or values that can't be scanned, show that the database is available.
+func (b *Breaker) Record(err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		// The caller gave up, which says nothing about the database
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !unavailable(err) {
+		if b.open {
+			logging.Logf(logrus.InfoLevel, "Database circuit breaker closed: database has recovered")
+		}
+		b.failures = 0
+		b.open = false
+		return
+	}
+
+	b.failures++
+	if !b.open && b.failures >= BreakerThreshold {
+		b.open = true
+		b.retryAt = time.Now().Add(breakerProbeInitial)
+		logging.Logf(logrus.WarnLevel, "Database circuit breaker opened after %d failures: %v", b.failures, err)
+		if !b.probing {
+			b.probing = true
+			go b.probeLoop()
+		}
+	}
+}
+
+// IsOpen returns true if calls are currently being rejected.
+func (b *Breaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// Stop the background probe, if running.
+func (b *Breaker) Stop() {
+	b.once.Do(func() { close(b.stop) })
+}
+
+// Pings the database until it recovers or the breaker is stopped.
+func (b *Breaker) probeLoop() {
+	interval := breakerProbeInitial
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-time.After(interval):
+		}
+
+		b.mu.Lock()
+		if !b.open {
+			// A call that was already running reached the database
+			b.probing = false
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), breakerProbeTimeout)
+		err := b.probe(ctx)
+		cancel()
+
+		b.mu.Lock()
+		if err == nil {
+			b.open = false
+			b.failures = 0
+			b.probing = false
+			b.mu.Unlock()
+			logging.Logf(logrus.InfoLevel, "Database circuit breaker closed: database has recovered")
+			return
+		}
+		interval = min(interval*2, breakerProbeMax)
+		b.retryAt = time.Now().Add(interval)
+		b.mu.Unlock()
+		logging.Logf(logrus.DebugLevel, "Database probe failed, retrying in %s: %v", interval, err)
+	}
+}
+
+// Returns true if err shows that the database could not be reached, rather than that the statement failed.
+func unavailable(err error) bool {
+	var pqErr *pq.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &pqErr):
+		// Connection exceptions, insufficient resources and operator intervention such as a shutdown
+		class := pqErr.Code.Class()
+		return class == "08" || class == "53" || class == "57"
+	case errors.As(err, &netErr), errors.Is(err, driver.ErrBadConn), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	default:
+		return false
+	}
+}
+
+// Circuit breakers of the connection pools opened by Open.
+var breakers sync.Map
+
+// Returns the circuit breaker of a connection pool.
+// Pools that weren't opened by Open get a breaker the first time they are used, which is stopped by Close.
+func breakerFor(conn *sql.DB) *Breaker {
+	if b, ok := breakers.Load(conn); ok {
+		return b.(*Breaker)
+	}
+	b, _ := breakers.LoadOrStore(conn, NewBreaker(conn.PingContext))
+	return b.(*Breaker)
+}
+
+// A transaction that records the result of its statements in the circuit breaker.
+type transaction struct {
+	*sql.Tx
+	breaker *Breaker
+}
+
+// Begin a transaction unless the circuit breaker is open.
+// Failing to begin the transaction, run a statement or commit counts towards opening the circuit.
+func begin(ctx context.Context, conn *sql.DB, breaker *Breaker, opts *sql.TxOptions) (*transaction, error) {
+	if err := breaker.Allow(); err != nil {
+		return nil, err
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	breaker.Record(err)
+	if err != nil {
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+	return &transaction{tx, breaker}, nil
+}
+
+// Ends the span of a statement in the transaction and records the result in the circuit breaker.
+func (t *transaction) endStatement(span trace.Span, err error) {
+	endStatement(span, err)
+	t.breaker.Record(err)
+}
+
+// Commit the transaction and record the result in the circuit breaker.
+func (t *transaction) Commit() error {
+	err := t.Tx.Commit()
+	t.breaker.Record(err)
+	return err
+}
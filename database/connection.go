@@ -3,50 +3,100 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"os"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/IV1201-Group-2/login-service/config"
 	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/metrics"
 	sq "github.com/Masterminds/squirrel"
 	// Imports Postgres driver.
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/sirupsen/logrus"
 )
 
 // Postgres uses $1, $2, etc for placeholders.
 var stmtBuilder = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
-// Opens connection and pings the database.
-// If the connection fails, ErrConnectionFailed is returned.
-func Open(url string) (*sql.DB, error) {
-	driver := strings.Split(url, ":")[0]
-	db, err := sql.Open(driver, url)
+// The delay between connection attempts starts at connectBackoffInitial and doubles up to connectBackoffMax.
+const (
+	connectBackoffInitial = 500 * time.Millisecond
+	connectBackoffMax     = 10 * time.Second
+)
+
+// Opens connection, pings the database, checks that the tables owned by this service exist
+// and creates the circuit breaker of the pool.
+// The ping is retried with exponential backoff in case the database is still starting up.
+// If the connection fails, ErrConnectionFailed is returned, and ErrSchemaOutdated if migrations are missing.
+func Open(cfg config.Database) (*sql.DB, error) {
+	driver := strings.Split(cfg.URL, ":")[0]
+	db, err := sql.Open(driver, cfg.URL)
 	if err != nil {
 		return nil, ErrConnectionFailed.Wrap(err)
 	}
 
-	maxConn, _ := strconv.Atoi(os.Getenv("DATABASE_MAX_CONNECTIONS"))
-	db.SetMaxOpenConns(maxConn)
-	// Always keep a single idle connection open
-	db.SetMaxIdleConns(1)
+	db.SetMaxOpenConns(cfg.MaxConnections)
+	db.SetMaxIdleConns(cfg.MaxIdleConnections)
 	db.SetConnMaxIdleTime(0)
 
-	if err = db.Ping(); err != nil {
-		return nil, ErrConnectionFailed.Wrap(err)
-	}
+	retries := cfg.ConnectRetries
 
-	// Periodic logging of database statistics
-	go func() {
-		for {
-			logging.Logf(logrus.DebugLevel,
-				"Database statistics: in use=%d idle=%d",
-				db.Stats().InUse, db.Stats().Idle)
-			time.Sleep(10 * time.Second)
+	backoff := connectBackoffInitial
+	for attempt := 1; ; attempt++ {
+		if err = db.Ping(); err == nil {
+			break
+		}
+		if attempt > retries {
+			db.Close()
+			return nil, ErrConnectionFailed.Wrap(err)
 		}
-	}()
+
+		logging.Logf(logrus.WarnLevel, "Database ping failed (attempt %d/%d), retrying in %s: %v",
+			attempt, retries+1, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, connectBackoffMax)
+	}
+
+	if err = checkSchema(context.Background(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// The repositories that use the pool share one circuit breaker, which is stopped by Close
+	breakers.Store(db, NewBreaker(db.PingContext))
+
+	// Export connection pool statistics until the database is closed.
+	// If the pool replaces another pool that is still draining, the new pool takes over the metrics.
+	collector := collectors.NewDBStatsCollector(db, driver)
+	statsCollectors.Range(func(old, oldCollector any) bool {
+		metrics.Registry.Unregister(oldCollector.(prometheus.Collector))
+		statsCollectors.Delete(old)
+		return true
+	})
+	if err := metrics.Registry.Register(collector); err != nil {
+		logging.Logf(logrus.WarnLevel, "Database statistics will not be exported: %v", err)
+	} else {
+		statsCollectors.Store(db, collector)
+	}
 
 	return db, nil
 }
+
+// Pool statistics collectors registered by Open.
+var statsCollectors sync.Map
+
+// Close stops background work associated with the connection and closes it.
+func Close(db *sql.DB) error {
+	if b, ok := breakers.LoadAndDelete(db); ok {
+		b.(*Breaker).Stop()
+	}
+	if collector, ok := statsCollectors.LoadAndDelete(db); ok {
+		metrics.Registry.Unregister(collector.(prometheus.Collector))
+	}
+	return db.Close()
+}
@@ -43,8 +43,22 @@ func (e *Error) Is(target error) bool {
 var (
 	// ErrConnectionFailed indicates that connection to the database failed.
 	ErrConnectionFailed = &Error{"connection failed", nil}
+	// ErrSchemaOutdated indicates that the migrations of the tables owned by this service have not been applied.
+	ErrSchemaOutdated = &Error{"schema outdated", nil}
 	// ErrQueryFailed indicates that an SQL query failed for an unknown reason.
 	ErrQueryFailed = &Error{"query failed", nil}
 	// ErrUserNotFound indicates that a user with the specificed identity couldn't be found.
 	ErrUserNotFound = &Error{"user not found in db", nil}
+	// ErrUserDisabled indicates that the user was found but their account has been disabled.
+	ErrUserDisabled = &Error{"user disabled", nil}
+	// ErrEmailUnverified indicates that the user was found but has not verified their e-mail address.
+	ErrEmailUnverified = &Error{"email not verified", nil}
+	// ErrEmailTaken indicates that the e-mail address already identifies another user.
+	ErrEmailTaken = &Error{"email taken", nil}
+	// ErrPersonalNumberTaken indicates that another user has the same personal number.
+	ErrPersonalNumberTaken = &Error{"personal number taken", nil}
+	// ErrSessionNotFound indicates that a session doesn't exist, has expired or has been revoked.
+	ErrSessionNotFound = &Error{"session not found in db", nil}
+	// ErrCircuitOpen indicates that the database is considered unavailable and the query was not attempted.
+	ErrCircuitOpen = &Error{"circuit breaker open", nil}
 )
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Columns that this service adds to the shared schema, by table.
+// The schema is maintained in the shared database repository, which applies the migrations in the
+// migrations directory of this repository. The service only checks that they have been applied.
+var requiredColumns = map[string][]string{
+	"auth_event":   {"auth_event_id", "event_type", "person_id", "identity", "ip", "user_agent", "outcome", "created_at"},
+	"auth_session": {"session_id", "person_id", "device", "ip", "created_at", "last_seen_at", "expires_at", "revoked_at"},
+	"person":       {"disabled", "email_verified"},
+}
+
+// Checks that the tables and columns owned by this service exist.
+// If any of them is missing, ErrSchemaOutdated lists them.
+func checkSchema(ctx context.Context, db *sql.DB) error {
+	tables := make([]string, 0, len(requiredColumns))
+	for table := range requiredColumns {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+
+	query := stmtBuilder.RunWith(db).
+		Select("table_name", "column_name").
+		From("information_schema.columns").
+		Where("table_schema = current_schema()").
+		Where(sq.Eq{"table_name": tables})
+
+	rows, err := query.QueryContext(ctx)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	for rows.Next() {
+		var table, column string
+		if err = rows.Scan(&table, &column); err != nil {
+			return ErrQueryFailed.Wrap(err)
+		}
+		found[table+"."+column] = true
+	}
+	if err = rows.Err(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	var missing []string
+	for _, table := range tables {
+		for _, column := range requiredColumns[table] {
+			if !found[table+"."+column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return ErrSchemaOutdated.Wrap(errors.New("missing " + strings.Join(missing, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/model"
+	sq "github.com/Masterminds/squirrel"
+)
+
+// SessionTouchInterval is how often the last-seen time of a session is updated.
+// Requests made more often than this don't write to the database.
+const SessionTouchInterval = time.Minute
+
+type SessionRepository struct {
+	conn    *sql.DB
+	breaker *Breaker
+}
+
+// NewSessionRepository creates a new repository from a database connection.
+func NewSessionRepository(conn *sql.DB) *SessionRepository {
+	return &SessionRepository{conn, breakerFor(conn)}
+}
+
+// Returns a condition that matches the sessions of a user that have not expired or been revoked.
+func activeSessions(personID int, now time.Time) sq.And {
+	return sq.And{
+		sq.Eq{"person_id": personID, "revoked_at": nil},
+		sq.Gt{"expires_at": now},
+	}
+}
+
+// Insert a new session.
+func (s *SessionRepository) Create(ctx context.Context, session model.Session) error {
+	tx, err := begin(ctx, s.conn, s.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Insert("auth_session").
+		Columns("session_id", "person_id", "device", "ip", "created_at", "last_seen_at", "expires_at").
+		Values(session.ID, session.PersonID, session.Device, session.IP, session.CreatedAt, session.LastSeen, session.ExpiresAt)
+
+	ctx, span := startStatement(ctx, "INSERT", "auth_session", query)
+	_, err = query.ExecContext(ctx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// List the sessions of a user that have not expired or been revoked, most recently used first.
+func (s *SessionRepository) List(ctx context.Context, personID int) ([]model.Session, error) {
+	tx, err := begin(ctx, s.conn, s.breaker, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Select("session_id", "person_id", "device", "ip", "created_at", "last_seen_at", "expires_at").
+		From("auth_session").
+		Where(activeSessions(personID, time.Now())).
+		OrderBy("last_seen_at DESC", "session_id")
+
+	queryCtx, span := startStatement(ctx, "SELECT", "auth_session", query)
+	rows, err := query.QueryContext(queryCtx)
+	if err != nil {
+		tx.endStatement(span, err)
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		var session model.Session
+		var device, ip sql.NullString
+
+		err = rows.Scan(&session.ID, &session.PersonID, &device, &ip, &session.CreatedAt, &session.LastSeen, &session.ExpiresAt)
+		if err != nil {
+			tx.endStatement(span, err)
+			return nil, ErrQueryFailed.Wrap(err)
+		}
+		session.Device = device.String
+		session.IP = ip.String
+
+		sessions = append(sessions, session)
+	}
+	err = rows.Err()
+	tx.endStatement(span, err)
+	if err != nil {
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+
+	return sessions, nil
+}
+
+// Touch checks that a session of the user is still active and updates when it was last seen.
+// Returns ErrSessionNotFound if the session doesn't belong to the user, has expired or has been revoked.
+func (s *SessionRepository) Touch(ctx context.Context, personID int, id string) error {
+	var lastSeen time.Time
+	now := time.Now()
+
+	tx, err := begin(ctx, s.conn, s.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Select("last_seen_at").
+		From("auth_session").
+		Where(append(activeSessions(personID, now), sq.Eq{"session_id": id}))
+
+	selectCtx, span := startStatement(ctx, "SELECT", "auth_session", query)
+	err = query.ScanContext(selectCtx, &lastSeen)
+	tx.endStatement(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionNotFound.Wrap(err)
+	} else if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	if now.Sub(lastSeen) >= SessionTouchInterval {
+		update := stmtBuilder.RunWith(tx).
+			Update("auth_session").
+			Set("last_seen_at", now).
+			Where(sq.Eq{"session_id": id})
+
+		updateCtx, span := startStatement(ctx, "UPDATE", "auth_session", update)
+		_, err = update.ExecContext(updateCtx)
+		tx.endStatement(span, err)
+		if err != nil {
+			return ErrQueryFailed.Wrap(err)
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// Revoke a session of the user, which stops its login tokens from working.
+// Returns ErrSessionNotFound if the session doesn't belong to the user, has expired or has already been revoked.
+func (s *SessionRepository) Revoke(ctx context.Context, personID int, id string) error {
+	now := time.Now()
+
+	tx, err := begin(ctx, s.conn, s.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Update("auth_session").
+		Set("revoked_at", now).
+		Where(append(activeSessions(personID, now), sq.Eq{"session_id": id}))
+
+	ctx, span := startStatement(ctx, "UPDATE", "auth_session", query)
+	result, err := query.ExecContext(ctx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+	// If no rows were affected, the session was not found
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrSessionNotFound.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// RevokeAll revokes every active session of a user, which stops all of their login tokens from working.
+func (s *SessionRepository) RevokeAll(ctx context.Context, personID int) error {
+	tx, err := begin(ctx, s.conn, s.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	if err = revokeSessions(ctx, tx, personID); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// Revokes every active session of a user as part of tx, which stops all of their login tokens from working.
+func revokeSessions(ctx context.Context, tx *transaction, personID int) error {
+	now := time.Now()
+
+	query := stmtBuilder.RunWith(tx).
+		Update("auth_session").
+		Set("revoked_at", now).
+		Where(activeSessions(personID, now))
+
+	ctx, span := startStatement(ctx, "UPDATE", "auth_session", query)
+	_, err := query.ExecContext(ctx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/IV1201-Group-2/login-service/tracing"
+	sq "github.com/Masterminds/squirrel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Starts a span for an SQL statement such as "SELECT person".
+// The statement text is recorded with placeholders, never with the arguments.
+func startStatement(ctx context.Context, operation string, table string, query sq.Sqlizer) (context.Context, trace.Span) {
+	statement, _, _ := query.ToSql()
+	return tracing.Start(ctx, operation+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+			semconv.DBStatement(statement),
+		))
+}
+
+// Ends a statement span. A statement that matched no rows is not considered failed.
+func endStatement(span trace.Span, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -1,42 +1,61 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"strings"
 
 	"github.com/IV1201-Group-2/login-service/model"
 	sq "github.com/Masterminds/squirrel"
 )
 
 type UserRepository struct {
-	conn *sql.DB
+	conn    *sql.DB
+	breaker *Breaker
 }
 
 // NewUserRepository creates a new repository from a database connection.
 func NewUserRepository(conn *sql.DB) *UserRepository {
-	return &UserRepository{conn}
+	return &UserRepository{conn, breakerFor(conn)}
 }
 
 // Query the repository for a user with the specified identity.
-func (u *UserRepository) Query(identity string) (*model.User, error) {
+// If the account has been disabled, the user is returned together with ErrUserDisabled.
+// If the user has not verified their e-mail address, the user is returned together with ErrEmailUnverified.
+func (u *UserRepository) Query(ctx context.Context, identity string) (*model.User, error) {
+	return u.queryUser(ctx, sq.Or{sq.Eq{"username": identity}, sq.Eq{"email": identity}})
+}
+
+// Get the user with the specified ID from the repository.
+// Disabled and unverified users are returned together with an error, like in Query.
+func (u *UserRepository) Get(ctx context.Context, id int) (*model.User, error) {
+	return u.queryUser(ctx, sq.Eq{"person_id": id})
+}
+
+// Query the repository for a single user matching the condition.
+func (u *UserRepository) queryUser(ctx context.Context, where sq.Sqlizer) (*model.User, error) {
 	var name, email, password sql.NullString
 	var user model.User
+	var disabled, verified bool
 
 	// Begin transaction:
 	// If user is spread across multiple tables all reads need to be done at the same time.
-	tx, err := u.conn.Begin()
+	tx, err := begin(ctx, u.conn, u.breaker, nil)
 	if err != nil {
-		return nil, ErrQueryFailed.Wrap(err)
+		return nil, err
 	}
 	// Transaction will be automatically rolled back if the function returns an error.
 	defer tx.Rollback()
 
 	query := stmtBuilder.RunWith(tx).
-		Select("person_id", "username", "email", "password", "role_id").
+		Select("person_id", "username", "email", "password", "role_id", "disabled", "email_verified").
 		From("person").
-		Where(sq.Or{sq.Eq{"username": identity}, sq.Eq{"email": identity}})
+		Where(where)
 
-	err = query.Scan(&user.ID, &name, &email, &password, &user.Role)
+	ctx, span := startStatement(ctx, "SELECT", "person", query)
+	err = query.ScanContext(ctx, &user.ID, &name, &email, &password, &user.Role, &disabled, &verified)
+	tx.endStatement(span, err)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrUserNotFound.Wrap(err)
 	} else if err != nil {
@@ -53,16 +72,23 @@ func (u *UserRepository) Query(identity string) (*model.User, error) {
 	user.Email = email.String
 	user.Password = password.String
 
+	if disabled {
+		return &user, ErrUserDisabled
+	}
+	if !verified {
+		return &user, ErrEmailUnverified
+	}
 	return &user, nil
 }
 
 // Update the password for a user in the repository with the specified ID.
-func (u *UserRepository) UpdatePassword(id int, password string) error {
+// Every session of the user is revoked at the same time, so that no token issued before the change keeps working.
+func (u *UserRepository) UpdatePassword(ctx context.Context, id int, password string) error {
 	// Begin transaction:
 	// If user is spread across multiple tables all writes need to be done at the same time.
-	tx, err := u.conn.Begin()
+	tx, err := begin(ctx, u.conn, u.breaker, nil)
 	if err != nil {
-		return ErrQueryFailed.Wrap(err)
+		return err
 	}
 	// Transaction will be automatically rolled back if the function returns an error.
 	defer tx.Rollback()
@@ -72,7 +98,452 @@ func (u *UserRepository) UpdatePassword(id int, password string) error {
 		Set("password", password).
 		Where(sq.Eq{"person_id": id})
 
-	result, err := query.Exec()
+	ctx, span := startStatement(ctx, "UPDATE", "person", query)
+	result, err := query.ExecContext(ctx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+	// If no rows were affected, the user was not found
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrUserNotFound.Wrap(err)
+	}
+
+	if err = revokeSessions(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// UserFilter restricts which users are listed.
+// Zero values match every user.
+type UserFilter struct {
+	// Case-insensitive substring of the username, e-mail address, name or surname
+	Search string
+	Role   *model.Role
+
+	Limit  uint64
+	Offset uint64
+}
+
+// Escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (f UserFilter) where() sq.And {
+	where := sq.And{}
+	if f.Search != "" {
+		pattern := "%" + likeEscaper.Replace(f.Search) + "%"
+		where = append(where, sq.Or{
+			sq.ILike{"username": pattern},
+			sq.ILike{"email": pattern},
+			sq.ILike{"name": pattern},
+			sq.ILike{"surname": pattern},
+		})
+	}
+	if f.Role != nil {
+		where = append(where, sq.Eq{"role_id": *f.Role})
+	}
+	return where
+}
+
+// Selects the status of users. The last login is the newest login token issued in the audit trail.
+func selectUserStatus(runner sq.BaseRunner) sq.SelectBuilder {
+	return stmtBuilder.RunWith(runner).
+		Select("person_id", "username", "email", "name", "surname", "role_id").
+		Column("coalesce(password, '') <> ''").
+		Column("disabled").
+		Column(sq.Expr("(SELECT max(created_at) FROM auth_event WHERE auth_event.person_id = person.person_id AND event_type = ?)",
+			model.AuthEventLoginTokenIssued)).
+		From("person")
+}
+
+// Scans a row selected by selectUserStatus.
+func scanUserStatus(row sq.RowScanner) (model.UserStatus, error) {
+	var status model.UserStatus
+	var username, email, name, surname sql.NullString
+	var lastLogin sql.NullTime
+
+	err := row.Scan(&status.ID, &username, &email, &name, &surname, &status.Role, &status.HasPassword, &status.Disabled, &lastLogin)
+	status.Username = username.String
+	status.Email = email.String
+	status.Name = name.String
+	status.Surname = surname.String
+	if lastLogin.Valid {
+		status.LastLogin = &lastLogin.Time
+	}
+	return status, err
+}
+
+// List users matching the filter, ordered by ID.
+// This function also returns the total number of matching users for pagination.
+func (u *UserRepository) List(ctx context.Context, filter UserFilter) ([]model.UserStatus, int, error) {
+	// Begin transaction:
+	// The count and the page need to be read from the same snapshot.
+	tx, err := begin(ctx, u.conn, u.breaker, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	var total int
+	countQuery := stmtBuilder.RunWith(tx).
+		Select("count(*)").
+		From("person").
+		Where(filter.where())
+
+	countCtx, span := startStatement(ctx, "SELECT", "person", countQuery)
+	err = countQuery.ScanContext(countCtx, &total)
+	tx.endStatement(span, err)
+	if err != nil {
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+
+	query := selectUserStatus(tx).
+		Where(filter.where()).
+		OrderBy("person_id").
+		Limit(filter.Limit).
+		Offset(filter.Offset)
+
+	queryCtx, span := startStatement(ctx, "SELECT", "person", query)
+	rows, err := query.QueryContext(queryCtx)
+	if err != nil {
+		tx.endStatement(span, err)
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+	defer rows.Close()
+
+	users := []model.UserStatus{}
+	for rows.Next() {
+		status, err := scanUserStatus(rows)
+		if err != nil {
+			tx.endStatement(span, err)
+			return nil, 0, ErrQueryFailed.Wrap(err)
+		}
+		users = append(users, status)
+	}
+	err = rows.Err()
+	tx.endStatement(span, err)
+	if err != nil {
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, 0, ErrQueryFailed.Wrap(err)
+	}
+
+	return users, total, nil
+}
+
+// Status returns the status of the user with the specified ID.
+func (u *UserRepository) Status(ctx context.Context, id int) (*model.UserStatus, error) {
+	tx, err := begin(ctx, u.conn, u.breaker, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := selectUserStatus(tx).Where(sq.Eq{"person_id": id})
+
+	ctx, span := startStatement(ctx, "SELECT", "person", query)
+	status, err := scanUserStatus(query.QueryRowContext(ctx))
+	tx.endStatement(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound.Wrap(err)
+	} else if err != nil {
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, ErrQueryFailed.Wrap(err)
+	}
+
+	return &status, nil
+}
+
+// Update the role of a user in the repository with the specified ID.
+func (u *UserRepository) UpdateRole(ctx context.Context, id int, role model.Role) error {
+	tx, err := begin(ctx, u.conn, u.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Update("person").
+		Set("role_id", role).
+		Where(sq.Eq{"person_id": id})
+
+	ctx, span := startStatement(ctx, "UPDATE", "person", query)
+	result, err := query.ExecContext(ctx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+	// If no rows were affected, the user was not found
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrUserNotFound.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// Disabled returns true if the account of the user with the specified ID has been disabled.
+func (u *UserRepository) Disabled(ctx context.Context, id int) (bool, error) {
+	_, disabled, err := u.Access(ctx, id)
+	return disabled, err
+}
+
+// Access returns the current role of the user with the specified ID and whether their account has been disabled.
+func (u *UserRepository) Access(ctx context.Context, id int) (model.Role, bool, error) {
+	var role model.Role
+	var disabled bool
+
+	tx, err := begin(ctx, u.conn, u.breaker, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, false, err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Select("role_id", "disabled").
+		From("person").
+		Where(sq.Eq{"person_id": id})
+
+	ctx, span := startStatement(ctx, "SELECT", "person", query)
+	err = query.ScanContext(ctx, &role, &disabled)
+	tx.endStatement(span, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, ErrUserNotFound.Wrap(err)
+	} else if err != nil {
+		return 0, false, ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return 0, false, ErrQueryFailed.Wrap(err)
+	}
+
+	return role, disabled, nil
+}
+
+// Disable or enable the account of a user in the repository with the specified ID.
+// Disabling an account also revokes all of the user's sessions, so enabling it again doesn't log them back in.
+func (u *UserRepository) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	tx, err := begin(ctx, u.conn, u.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Update("person").
+		Set("disabled", disabled).
+		Where(sq.Eq{"person_id": id})
+
+	updateCtx, span := startStatement(ctx, "UPDATE", "person", query)
+	result, err := query.ExecContext(updateCtx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+	// If no rows were affected, the user was not found
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrUserNotFound.Wrap(err)
+	}
+
+	if disabled {
+		if err := revokeSessions(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// Returns a condition that matches users identified by email, either as their e-mail address or username.
+// Identities are compared case-insensitively, since e-mail providers ignore case.
+func identityTaken(email string) sq.Sqlizer {
+	return sq.Expr("(lower(email) = lower(?) OR lower(username) = lower(?))", email, email)
+}
+
+// Returns an error for each identity that already belongs to another user.
+// The user with the specified ID, if not nil, is ignored. An empty personal number is never considered taken.
+func conflicts(ctx context.Context, tx *transaction, userID *int, email string, personalNumber string) error {
+	var emailTaken, personalNumberTaken bool
+
+	emailWhere := sq.And{identityTaken(email)}
+	personalNumberWhere := sq.And{sq.NotEq{"pnr": ""}, sq.Eq{"pnr": personalNumber}}
+	if userID != nil {
+		emailWhere = append(emailWhere, sq.NotEq{"person_id": *userID})
+		personalNumberWhere = append(personalNumberWhere, sq.NotEq{"person_id": *userID})
+	}
+
+	// Subqueries use question placeholders, which are numbered once the outer query is built
+	query := stmtBuilder.RunWith(tx).
+		Select().
+		Column(sq.Expr("EXISTS (?)", sq.Select("1").From("person").Where(emailWhere))).
+		Column(sq.Expr("EXISTS (?)", sq.Select("1").From("person").Where(personalNumberWhere)))
+
+	ctx, span := startStatement(ctx, "SELECT", "person", query)
+	err := query.ScanContext(ctx, &emailTaken, &personalNumberTaken)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	var errs []error
+	if emailTaken {
+		errs = append(errs, ErrEmailTaken)
+	}
+	if personalNumberTaken {
+		errs = append(errs, ErrPersonalNumberTaken)
+	}
+	return errors.Join(errs...)
+}
+
+// Create an applicant with an unverified e-mail address and return their ID.
+// Returns ErrEmailTaken or ErrPersonalNumberTaken (or both) if another user has the same identity.
+func (u *UserRepository) Create(ctx context.Context, registration model.Registration) (int, error) {
+	// Begin transaction:
+	// No other user can be created with the same identity between the check and the insert.
+	tx, err := begin(ctx, u.conn, u.breaker, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return 0, err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	if err := conflicts(ctx, tx, nil, registration.Email, registration.PersonalNumber); err != nil {
+		return 0, err
+	}
+
+	var id int
+	query := stmtBuilder.RunWith(tx).
+		Insert("person").
+		Columns("name", "surname", "pnr", "email", "password", "role_id", "email_verified").
+		Values(registration.Name, registration.Surname, registration.PersonalNumber, registration.Email,
+			registration.Password, model.RoleApplicant, false).
+		Suffix("RETURNING person_id")
+
+	insertCtx, span := startStatement(ctx, "INSERT", "person", query)
+	err = query.QueryRowContext(insertCtx).Scan(&id)
+	tx.endStatement(span, err)
+	if err != nil {
+		return 0, ErrQueryFailed.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return 0, ErrQueryFailed.Wrap(err)
+	}
+
+	return id, nil
+}
+
+// Mark the e-mail address of a user as verified.
+// The address must still be the one that the verification link was sent to, otherwise ErrUserNotFound is returned.
+func (u *UserRepository) VerifyEmail(ctx context.Context, id int, email string) error {
+	tx, err := begin(ctx, u.conn, u.breaker, nil)
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	query := stmtBuilder.RunWith(tx).
+		Update("person").
+		Set("email_verified", true).
+		Where(sq.Eq{"person_id": id, "email": email})
+
+	ctx, span := startStatement(ctx, "UPDATE", "person", query)
+	result, err := query.ExecContext(ctx)
+	tx.endStatement(span, err)
+	if err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+	// If no rows were affected, the user was not found
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrUserNotFound.Wrap(err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// EmailAvailable returns ErrEmailTaken if the e-mail address identifies a user other than the one with the specified ID.
+func (u *UserRepository) EmailAvailable(ctx context.Context, id int, email string) error {
+	tx, err := begin(ctx, u.conn, u.breaker, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	if err := conflicts(ctx, tx, &id, email, ""); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return ErrQueryFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// Change the e-mail address of a user from current to email and mark it as verified.
+// The user must still have the current address, otherwise ErrUserNotFound is returned.
+// Returns ErrEmailTaken if the new address identifies another user.
+func (u *UserRepository) ChangeEmail(ctx context.Context, id int, current string, email string) error {
+	// Begin transaction:
+	// No other user can take the address between the check and the update.
+	tx, err := begin(ctx, u.conn, u.breaker, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	// Transaction will be automatically rolled back if the function returns an error.
+	defer tx.Rollback()
+
+	if err := conflicts(ctx, tx, &id, email, ""); err != nil {
+		return err
+	}
+
+	query := stmtBuilder.RunWith(tx).
+		Update("person").
+		Set("email", email).
+		Set("email_verified", true).
+		Where(sq.Eq{"person_id": id, "email": current})
+
+	updateCtx, span := startStatement(ctx, "UPDATE", "person", query)
+	result, err := query.ExecContext(updateCtx)
+	tx.endStatement(span, err)
 	if err != nil {
 		return ErrQueryFailed.Wrap(err)
 	}
@@ -0,0 +1,255 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Backups are named after the log file with the time of rotation appended, for example "service-20240301T120000.000.log.gz".
+const backupTimeFormat = "20060102T150405.000"
+
+// FileOptions controls when a log file is rotated and how many backups are kept.
+type FileOptions struct {
+	// Path to the log file.
+	Path string
+	// Rotate the file once it would grow beyond this many bytes. Zero disables size-based rotation.
+	MaxSize int64
+	// Rotate the file once it has been written to for this long. Zero disables age-based rotation.
+	MaxAge time.Duration
+	// Number of compressed backups to keep. Zero keeps all backups.
+	MaxBackups int
+}
+
+// RotatingFile is a log file that rotates itself by size and age and keeps gzip-compressed backups.
+// It can also be reopened, which allows external tools such as logrotate to move the file.
+type RotatingFile struct {
+	options FileOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// Tracks backups that are being compressed in the background.
+	compressing sync.WaitGroup
+	stop        chan struct{}
+	once        sync.Once
+}
+
+// OpenFile opens a log file for appending, creating it if necessary.
+func OpenFile(options FileOptions) (*RotatingFile, error) {
+	f := &RotatingFile{options: options, stop: make(chan struct{})}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.options.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644) // #nosec G302
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// Write a log line to the file, rotating it first if necessary.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooLarge := f.options.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.options.MaxSize
+	tooOld := f.options.MaxAge > 0 && time.Since(f.openedAt) >= f.options.MaxAge
+	if tooLarge || tooOld {
+		if err := f.rotate(); err != nil {
+			// Keep writing to the current file rather than losing the line,
+			// and try again after another MaxSize bytes or MaxAge
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file: %v\n", err)
+			f.size = 0
+			f.openedAt = time.Now()
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Moves the current file to a backup and opens a new file in its place.
+// The current file is only closed once the new file is open, so it can still be written to if rotation fails.
+func (f *RotatingFile) rotate() error {
+	ext := filepath.Ext(f.options.Path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.options.Path, ext), time.Now().Format(backupTimeFormat), ext)
+	if err := os.Rename(f.options.Path, backup); err != nil {
+		return err
+	}
+
+	old := f.file
+	if err := f.open(); err != nil {
+		// Move the file back, so that the lines written until the next attempt end up at the configured path
+		if renameErr := os.Rename(backup, f.options.Path); renameErr != nil {
+			return errors.Join(err, renameErr)
+		}
+		return err
+	}
+	if err := old.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to close rotated log file: %v\n", err)
+	}
+
+	f.compressing.Add(1)
+	go func() {
+		defer f.compressing.Done()
+		if err := compress(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to compress log backup %s: %v\n", backup, err)
+		}
+		f.prune()
+	}()
+
+	return nil
+}
+
+// Compresses a backup with gzip and removes the uncompressed file.
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644) // #nosec G302
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// Backups returns the paths of all compressed backups, oldest first.
+// Only files named like backups of this file are returned, so other files such as "service-old.log.gz" are left alone.
+func (f *RotatingFile) Backups() []string {
+	dir, name := filepath.Dir(f.options.Path), filepath.Base(f.options.Path)
+	ext := filepath.Ext(name)
+	prefix, suffix := strings.TrimSuffix(name, ext)+"-", ext+".gz"
+
+	entries, _ := os.ReadDir(dir)
+	var backups []string
+	for _, entry := range entries {
+		timestamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		timestamp, ok = strings.CutSuffix(timestamp, suffix)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, timestamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, entry.Name()))
+	}
+	// The timestamp format sorts lexicographically
+	slices.Sort(backups)
+	return backups
+}
+
+// Removes the oldest backups until at most MaxBackups remain.
+func (f *RotatingFile) prune() {
+	if f.options.MaxBackups <= 0 {
+		return
+	}
+	backups := f.Backups()
+	for len(backups) > f.options.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+// Reopen closes the file and opens it again at the same path.
+// This should be called after an external tool has moved the file.
+// If the file can't be opened, the old file is kept open and written to.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	old := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// ReopenOnSIGUSR1 reopens the file every time the process receives SIGUSR1.
+// SIGHUP is left for reloading secrets.
+func (f *RotatingFile) ReopenOnSIGUSR1() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-signals:
+				if err := f.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to reopen log file: %v\n", err)
+					continue
+				}
+				Logf(logrus.InfoLevel, "Reopened log file after SIGUSR1")
+			}
+		}
+	}()
+}
+
+// Close the file after waiting for backups to be compressed.
+func (f *RotatingFile) Close() error {
+	f.once.Do(func() { close(f.stop) })
+	f.compressing.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -2,9 +2,14 @@
 package logging
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"time"
 
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 )
@@ -12,39 +17,140 @@ import (
 // TimestampFormat is a custom format for time.Format.
 const TimestampFormat = "2006-01-02 15:04"
 
+const (
+	// FormatText is the human-readable log format.
+	FormatText = "text"
+	// FormatJSON outputs one JSON object per log line.
+	FormatJSON = "json"
+)
+
+// Names of the structured fields attached to log lines.
+const (
+	FieldIP        = "ip"
+	FieldRequestID = "request_id"
+	FieldRoute     = "route"
+	FieldStatus    = "status"
+	FieldLatency   = "latency_ms"
+	FieldUserID    = "user_id"
+)
+
+// ErrUnknownFormat indicates that the log format is neither "text" nor "json".
+var ErrUnknownFormat = errors.New("unknown log format")
+
 // Logger is a Logrus instance with a customized configuration.
 var logger *logrus.Logger
 
+// If set, log lines are formatted as JSON and messages are not prefixed with their origin.
+var jsonFormat bool
+
+// The logger starts with the default configuration, which is changed with SetLevel, SetFormat, SetRedaction and SetOutput.
 func init() {
-	out := os.Stdout
-	if filename, ok := os.LookupEnv("LOG_FILE"); ok {
-		out, _ = os.Open(filename)
+	logger = &logrus.Logger{
+		Out:   os.Stdout,
+		Level: logrus.InfoLevel,
+		Hooks: make(logrus.LevelHooks),
 	}
+	_ = SetFormat(FormatText)
+	_ = SetRedaction(RedactOff, nil)
+}
 
-	level := logrus.InfoLevel
-	if levelstr, ok := os.LookupEnv("LOG_LEVEL"); ok {
-		level, _ = logrus.ParseLevel(levelstr)
+// SetLevel changes the minimum level of messages that are logged.
+func SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
 	}
+	logger.SetLevel(parsed)
+	return nil
+}
 
-	logger = &logrus.Logger{
-		Out:   out,
-		Level: level,
-		Formatter: &logrus.TextFormatter{
+// SetFormat switches between the text and JSON log formats.
+func SetFormat(format string) error {
+	switch format {
+	case FormatText:
+		logger.SetFormatter(&logrus.TextFormatter{
 			ForceColors:               true,
 			EnvironmentOverrideColors: true,
 			FullTimestamp:             true,
 			TimestampFormat:           TimestampFormat,
 			DisableLevelTruncation:    true,
-		},
+		})
+		jsonFormat = false
+	case FormatJSON:
+		logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+		})
+		jsonFormat = true
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return nil
+}
+
+// SetOutput changes where log lines are written.
+func SetOutput(out io.Writer) {
+	logger.SetOutput(out)
+}
+
+// Text logs are prefixed with the origin of the message, JSON logs have it as a field.
+func prefix(origin string, format string) string {
+	if jsonFormat {
+		return format
+	}
+	return fmt.Sprintf("[%s] %s", origin, format)
+}
+
+// Returns the claims of the token provided by the user, if any.
+func userClaims(c echo.Context) (*model.UserClaims, bool) {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := token.Claims.(*model.UserClaims)
+	return claims, ok
+}
+
+// UserID returns the ID of the user making the request, if known.
+func UserID(c echo.Context) (int, bool) {
+	if id, ok := c.Get(userIDKey).(int); ok {
+		return id, true
+	}
+	if claims, ok := userClaims(c); ok {
+		return claims.User.ID, true
+	}
+	return 0, false
+}
+
+// SetUserID attaches the ID of an authenticated user to all following log lines for this request.
+func SetUserID(c echo.Context, id int) {
+	c.Set(userIDKey, id)
+}
+
+// Fields that are known for every log line in a handler.
+func contextFields(c echo.Context) logrus.Fields {
+	fields := logrus.Fields{
+		FieldIP:    c.RealIP(),
+		FieldRoute: c.Path(),
+		piiField:   knownPII(c),
+	}
+	if id := RequestID(c); id != "" {
+		fields[FieldRequestID] = id
+	}
+	if id, ok := UserID(c); ok {
+		fields[FieldUserID] = id
 	}
+	return fields
 }
 
 // Log a message that occurred in the application.
 func Logf(level logrus.Level, format string, args ...any) {
-	logger.Logf(level, fmt.Sprintf("[Service] %s\n", format), args...)
+	logger.Logf(level, prefix("Service", format), args...)
 }
 
 // Log a message that occurred in a handler.
+// Arguments that are personal information of the user making the request, such as their identity, are redacted.
 func Logcf(level logrus.Level, c echo.Context, format string, args ...any) {
-	logger.Logf(level, fmt.Sprintf("[%s] %s\n", c.RealIP(), format), args...)
+	known := knownPII(c)
+	message := fmt.Sprintf(format, redaction.args(args, known)...)
+	logger.WithFields(contextFields(c)).Log(level, prefix(redaction.known(c.RealIP(), known), message))
 }
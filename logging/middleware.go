@@ -1,27 +1,61 @@
 package logging
 
 import (
+	"fmt"
+	"net/http"
+	"slices"
 	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
 // Echo middleware to log requests using Logrus.
-func Middleware() echo.MiddlewareFunc {
+// Requests to any of the routes in skipPaths are not logged.
+func Middleware(skipPaths ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if slices.Contains(skipPaths, c.Path()) {
+				return next(c)
+			}
+
 			start := time.Now()
-			err := next(c)
+			// Handle the error here so the status code is known when logging
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
 			elapsed := time.Since(start).Milliseconds()
 
-			logger.Infof("[%s] %s %s %s in %dms",
-				c.RealIP(),
+			fields := contextFields(c)
+			fields[FieldStatus] = c.Response().Status
+			fields[FieldLatency] = elapsed
+
+			message := fmt.Sprintf("%s %s %s in %dms",
 				c.Request().Proto,
 				c.Request().Method,
-				c.Request().RequestURI,
+				loggedURI(c.Request()),
 				elapsed)
+			logger.WithFields(fields).Info(prefix(redaction.known(c.RealIP(), knownPII(c)), message))
+
+			return nil
+		}
+	}
+}
 
-			return err
+// Query parameters that contain tokens, such as e-mail verification links. Their values are never logged.
+var secretQueryParams = []string{"token"}
+
+// Returns the request URI with the values of secret query parameters hidden.
+func loggedURI(r *http.Request) string {
+	query := r.URL.Query()
+	redacted := false
+	for _, param := range secretQueryParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
 		}
 	}
+	if !redacted {
+		return r.RequestURI
+	}
+	return r.URL.Path + "?" + query.Encode()
 }
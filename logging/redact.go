@@ -0,0 +1,206 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RedactOff logs personal information as-is.
+	RedactOff = "off"
+	// RedactMask replaces personal information with a partially hidden version.
+	RedactMask = "mask"
+	// RedactHash replaces personal information with a keyed hash so log lines can still be correlated.
+	RedactHash = "hash"
+)
+
+// ErrUnknownRedaction indicates that the redaction mode is not known.
+var ErrUnknownRedaction = errors.New("unknown redaction mode")
+
+// Key used to pass known personal information from Logcf to the redaction hook, which redacts fields with those values.
+const piiField = "logging.pii"
+
+// Key used to store the identity submitted by the user in the Echo context.
+const identityKey = "logging.identity"
+
+var (
+	// Matches e-mail addresses, including URL-encoded addresses in request URIs.
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+(?:@|%40)[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Matches anything that could be an IPv4 or IPv6 address, optionally with a port such as 10.0.0.1:54321 or [::1]:8080.
+	// Candidates are verified with net.ParseIP.
+	ipPattern = regexp.MustCompile(`\[[0-9A-Fa-f:.]+\](?::[0-9]+)?|[0-9A-Fa-f]*[:.][0-9A-Fa-f:.]*[0-9A-Fa-f]`)
+)
+
+// Logrus hook that removes personal information from every log line before it is written.
+type redactionHook struct {
+	mode string
+	key  []byte
+}
+
+func (h *redactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactionHook) Fire(entry *logrus.Entry) error {
+	known, _ := entry.Data[piiField].([]string)
+	delete(entry.Data, piiField)
+
+	if h.mode == RedactOff {
+		return nil
+	}
+
+	entry.Message = h.text(entry.Message)
+	for key, value := range entry.Data {
+		if str, ok := value.(string); ok {
+			entry.Data[key] = h.text(h.known(str, known))
+		}
+	}
+	return nil
+}
+
+// Redacts value if it is known personal information.
+// Only whole values are compared, since a short identity could otherwise match parts of unrelated words.
+func (h *redactionHook) known(value string, known []string) string {
+	if value != "" && slices.Contains(known, value) {
+		return h.value(value)
+	}
+	return value
+}
+
+// Returns the format arguments of a log line with known personal information redacted.
+func (h *redactionHook) args(args []any, known []string) []any {
+	if h.mode == RedactOff {
+		return args
+	}
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		if str, ok := arg.(string); ok {
+			arg = h.known(str, known)
+		}
+		redacted[i] = arg
+	}
+	return redacted
+}
+
+// Redacts a single value.
+func (h *redactionHook) value(value string) string {
+	if h.mode == RedactHash {
+		mac := hmac.New(sha256.New, h.key)
+		mac.Write([]byte(value))
+		return "pii:" + hex.EncodeToString(mac.Sum(nil))[:12]
+	}
+
+	switch {
+	case emailPattern.MatchString(value):
+		// The local part can contain "%" and, in quotes, "@", so only the domain after the last "@" is kept
+		at := strings.LastIndex(value, "@")
+		if at < 0 {
+			at = strings.LastIndex(value, "%40")
+		}
+		return value[:1] + "***" + value[at:]
+	case net.ParseIP(value) != nil && strings.Contains(value, "."):
+		return value[:strings.LastIndex(value, ".")] + ".x"
+	case net.ParseIP(value) != nil:
+		return value[:strings.LastIndex(value, ":")] + ":x"
+	default:
+		return value[:1] + "***"
+	}
+}
+
+// Redacts e-mail addresses and IP addresses in text.
+func (h *redactionHook) text(text string) string {
+	text = emailPattern.ReplaceAllStringFunc(text, h.value)
+	return ipPattern.ReplaceAllStringFunc(text, h.address)
+}
+
+// Redacts the IP address in candidate, keeping the port if there is one.
+// Candidates that don't contain an IP address are returned as-is.
+func (h *redactionHook) address(candidate string) string {
+	if net.ParseIP(candidate) != nil {
+		return h.value(candidate)
+	}
+	host, port, err := net.SplitHostPort(candidate)
+	if err != nil {
+		// An IPv6 address in brackets without a port
+		host, port = strings.TrimSuffix(strings.TrimPrefix(candidate, "["), "]"), ""
+	}
+	if net.ParseIP(host) == nil {
+		return candidate
+	}
+	redacted := h.value(host)
+	if strings.HasPrefix(candidate, "[") {
+		redacted = "[" + redacted + "]"
+	}
+	if port != "" {
+		redacted += ":" + port
+	}
+	return redacted
+}
+
+// The active redaction hook, replaced by SetRedaction.
+var redaction = &redactionHook{mode: RedactOff}
+
+// SetRedaction changes how personal information is removed from log lines.
+// The key is only used by the hash mode, and a random key is generated if it is empty.
+func SetRedaction(mode string, key []byte) error {
+	switch mode {
+	case RedactOff, RedactMask:
+	case RedactHash:
+		if len(key) == 0 {
+			key = make([]byte, 32)
+			_, _ = rand.Read(key)
+			Logf(logrus.WarnLevel, "No redaction key set, hashes will change when the service restarts")
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownRedaction, mode)
+	}
+
+	hooks := make(logrus.LevelHooks)
+	redaction = &redactionHook{mode: mode, key: key}
+	hooks.Add(redaction)
+	logger.ReplaceHooks(hooks)
+	return nil
+}
+
+// SetIdentity remembers the identity submitted by the user.
+// The identity is treated as personal information in all following log lines for this request.
+func SetIdentity(c echo.Context, identity string) {
+	c.Set(identityKey, identity)
+}
+
+// Identity returns the identity submitted by the user, or the identity in their token.
+func Identity(c echo.Context) string {
+	if identity, ok := c.Get(identityKey).(string); ok {
+		return identity
+	}
+	if claims, ok := userClaims(c); ok {
+		if claims.User.Username != "" {
+			return claims.User.Username
+		}
+		return claims.User.Email
+	}
+	return ""
+}
+
+// Personal information that is known to belong to the user making the request.
+func knownPII(c echo.Context) []string {
+	known := []string{c.RealIP()}
+	if identity, ok := c.Get(identityKey).(string); ok {
+		known = append(known, identity)
+	}
+	if claims, ok := userClaims(c); ok {
+		known = append(known, claims.User.Username, claims.User.Email)
+	}
+	return known
+}
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Represents an error that occurred while sending e-mail.
+type Error struct {
+	// Description of the error for logging.
+	Description string `json:"description"`
+	// Internal wrapped error. Not visible to users.
+	Internal error `json:"-"`
+}
+
+// Describes the mail error.
+func (e *Error) Error() string {
+	if e.Internal != nil {
+		return fmt.Sprintf("%s: %v", e.Description, e.Internal)
+	}
+	return e.Description
+}
+
+// Attaches an internal error to a mail error.
+func (e *Error) Wrap(err error) *Error {
+	return &Error{Description: e.Description, Internal: err}
+}
+
+// If an error has been wrapped in a.Internal, return the error.
+func (e *Error) Unwrap() error {
+	return e.Internal
+}
+
+// Mail errors are considered equivalent if their description is equivalent.
+func (e *Error) Is(target error) bool {
+	var mailErr *Error
+	if errors.As(target, &mailErr) {
+		return e.Description == mailErr.Description
+	}
+	return false
+}
+
+var (
+	// ErrSendFailed indicates that the SMTP server could not be reached or refused the message.
+	ErrSendFailed = &Error{"send failed", nil}
+)
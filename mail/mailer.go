@@ -0,0 +1,95 @@
+// The package mail sends e-mail to users, such as links that verify their e-mail address.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// Message is a plain text e-mail to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends e-mail. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New creates the mailer described by the configuration, or returns nil if mail is not configured.
+// E-mail is only written to the log if log mail has been enabled explicitly.
+func New(cfg config.Mail) Mailer {
+	if !cfg.Enabled() {
+		return nil
+	}
+	if cfg.Log {
+		logging.Logf(logrus.WarnLevel, "E-mail is written to the log instead of being sent, including links that grant access to accounts")
+		return LogMailer{}
+	}
+	return NewSMTPMailer(cfg)
+}
+
+// LogMailer writes e-mail to the log instead of sending it.
+// This is intended for development, since the log will contain links that grant access to accounts.
+type LogMailer struct{}
+
+// Send logs the message.
+func (LogMailer) Send(_ context.Context, message Message) error {
+	logging.Logf(logrus.InfoLevel, "E-mail to %s with subject %q:\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// SMTPMailer sends e-mail through an SMTP server, using STARTTLS if the server supports it.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a mailer that sends e-mail through the configured SMTP server.
+func NewSMTPMailer(cfg config.Mail) *SMTPMailer {
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+	}
+	return &SMTPMailer{addr: cfg.SMTPAddr, auth: auth, from: cfg.From}
+}
+
+// Send delivers the message to the SMTP server.
+// The context is only used for tracing, since net/smtp can't be cancelled.
+func (m *SMTPMailer) Send(ctx context.Context, message Message) (err error) {
+	_, span := tracing.Start(ctx, "mail.Send")
+	defer func() { tracing.End(span, err) }()
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{message.To}, m.format(message)); err != nil {
+		return ErrSendFailed.Wrap(err)
+	}
+	return nil
+}
+
+// Formats a message with the headers required by RFC 5322.
+func (m *SMTPMailer) format(message Message) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", m.from)
+	fmt.Fprintf(&builder, "To: %s\r\n", message.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&builder, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
@@ -1,25 +1,137 @@
 package main
 
 import (
+	"context"
+	"net/http"
 	"os"
 
 	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/config"
 	"github.com/IV1201-Group-2/login-service/database"
 	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tracing"
 	"github.com/sirupsen/logrus"
 )
 
+// The log file opened by setupLogging, if any.
+var logFile *logging.RotatingFile
+
+// Logs an error that prevents the service from running and exits.
+// Deferred calls don't run when the process exits, so the log file is closed first to finish writing.
+func fatalf(format string, args ...any) {
+	logging.Logf(logrus.FatalLevel, format, args...)
+	if logFile != nil {
+		logFile.Close()
+	}
+	os.Exit(1)
+}
+
+// Applies the logging configuration and opens the log file, if any.
+func setupLogging(cfg config.Log) (*logging.RotatingFile, error) {
+	if err := logging.SetLevel(cfg.Level); err != nil {
+		return nil, err
+	}
+	if err := logging.SetFormat(cfg.Format); err != nil {
+		return nil, err
+	}
+	if err := logging.SetRedaction(cfg.Redaction, []byte(cfg.RedactionKey)); err != nil {
+		return nil, err
+	}
+	if cfg.File == "" {
+		return nil, nil
+	}
+
+	file, err := logging.OpenFile(logging.FileOptions{
+		Path:       cfg.File,
+		MaxSize:    cfg.FileMaxSize * 1024 * 1024,
+		MaxAge:     cfg.FileMaxAge,
+		MaxBackups: cfg.FileMaxBackups,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// External tools such as logrotate send SIGUSR1 after moving the file
+	file.ReopenOnSIGUSR1()
+	logging.SetOutput(file)
+	return file, nil
+}
+
 func main() {
-	db, err := database.Open(os.Getenv("DATABASE_URL"))
+	cfg, err := config.Load()
 	if err != nil {
-		logging.Logf(logrus.FatalLevel, "Database init error: %v", err)
+		fatalf("Configuration error: %v", err)
 	}
-	defer db.Close()
 
-	srv, err := api.NewServer(db)
+	logFile, err = setupLogging(cfg.Log)
 	if err != nil {
-		logging.Logf(logrus.FatalLevel, "Server init error: %v", err)
+		fatalf("Logging init error: %v", err)
 	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
+
+	service.Configure(cfg.Auth)
 
-	logging.Logf(logrus.FatalLevel, "%v", srv.Start(":"+os.Getenv("PORT")))
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		fatalf("Tracing init error: %v", err)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		fatalf("Database init error: %v", err)
+	}
+
+	srv, reloader, err := api.NewServer(db, cfg)
+	if err != nil {
+		database.Close(db)
+		fatalf("Server init error: %v", err)
+	}
+
+	// Reload secrets on SIGHUP or when a secret file changes
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	go reloader.Watch(watchCtx, api.DefaultSecretPollInterval)
+
+	var servers []*http.Server
+	if cfg.MetricsPort != "" {
+		servers = append(servers, api.NewMetricsServer(":"+cfg.MetricsPort))
+	} else {
+		logging.Logf(logrus.InfoLevel, "Metrics are not served, set METRICS_PORT to serve them")
+	}
+
+	var serveErr error
+	if cfg.TLS.Enabled() {
+		cert, err := api.LoadCertificate(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			reloader.Close(context.Background())
+			fatalf("TLS init error: %v", err)
+		}
+		// Reload the certificate when it is renewed
+		go cert.Watch(watchCtx, api.DefaultSecretPollInterval)
+
+		options := api.TLSOptions{Config: api.NewTLSConfig(cert)}
+		if cfg.TLS.RedirectPort != "" {
+			options.RedirectAddress = ":" + cfg.TLS.RedirectPort
+		}
+		serveErr = api.ServeTLS(srv, ":"+cfg.Port, cfg.ShutdownTimeout, options, servers...)
+	} else {
+		serveErr = api.Serve(srv, ":"+cfg.Port, cfg.ShutdownTimeout, servers...)
+	}
+	stopWatching()
+
+	// Stop background work and close all connections once requests have been drained
+	closeCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	if err := reloader.Close(closeCtx); err != nil {
+		logging.Logf(logrus.ErrorLevel, "Database close error: %v", err)
+	}
+	cancel()
+	// Flush spans that haven't been exported yet
+	if err := shutdownTracing(context.Background()); err != nil {
+		logging.Logf(logrus.ErrorLevel, "Tracing shutdown error: %v", err)
+	}
+	if serveErr != nil {
+		fatalf("Server error: %v", serveErr)
+	}
+	logging.Logf(logrus.InfoLevel, "Shutdown complete")
 }
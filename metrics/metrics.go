@@ -0,0 +1,86 @@
+// The package metrics exposes operational statistics about the service in Prometheus format.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// All metrics are prefixed with this namespace.
+const namespace = "login_service"
+
+// OutcomeSuccess is the outcome label used when a request succeeded.
+const OutcomeSuccess = "success"
+
+// Registry contains all metrics exported by the service.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// LoginOutcomes counts login attempts by outcome (success or API error type).
+	LoginOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Number of login attempts by outcome.",
+	}, []string{"outcome"})
+	// ResetOutcomes counts password reset attempts by outcome (success or API error type).
+	ResetOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reset_attempts_total",
+		Help:      "Number of password reset attempts by outcome.",
+	}, []string{"outcome"})
+	// PasswordChangeOutcomes counts password change attempts by outcome (success or API error type).
+	PasswordChangeOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "password_change_attempts_total",
+		Help:      "Number of password change attempts by outcome.",
+	}, []string{"outcome"})
+	// EmailChangeOutcomes counts e-mail change attempts by outcome (success or API error type).
+	EmailChangeOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "email_change_attempts_total",
+		Help:      "Number of e-mail change attempts by outcome.",
+	}, []string{"outcome"})
+	// TokensIssued counts signed tokens by usage (login or reset).
+	TokensIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_issued_total",
+		Help:      "Number of tokens issued by usage.",
+	}, []string{"usage"})
+
+	// HandlerLatency measures how long each route takes to handle a request.
+	HandlerLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent handling requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	// BcryptDuration measures how long it takes to compare a password with its Bcrypt hash.
+	BcryptDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "bcrypt_compare_duration_seconds",
+		Help:      "Time spent comparing passwords with Bcrypt.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 8),
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		LoginOutcomes,
+		ResetOutcomes,
+		PasswordChangeOutcomes,
+		EmailChangeOutcomes,
+		TokensIssued,
+		HandlerLatency,
+		BcryptDuration,
+	)
+}
+
+// Handler serves all metrics in Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
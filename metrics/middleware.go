@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// UnmatchedRoute is the route and method label of requests that don't match a route.
+// Using the requested path and method would let clients create any number of label values.
+const UnmatchedRoute = "unmatched"
+
+// Echo middleware to measure handler latency.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route, method := c.Path(), c.Request().Method
+			if errors.Is(err, echo.ErrNotFound) || errors.Is(err, echo.ErrMethodNotAllowed) {
+				route, method = UnmatchedRoute, UnmatchedRoute
+			}
+			HandlerLatency.
+				WithLabelValues(route, method).
+				Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,14 @@
+-- Audit trail of authentication events, written by the login service.
+CREATE TABLE IF NOT EXISTS auth_event (
+	auth_event_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	event_type character varying(32) NOT NULL,
+	person_id bigint,
+	identity character varying(255),
+	ip character varying(64),
+	user_agent character varying(512),
+	outcome character varying(64) NOT NULL,
+	created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS auth_event_person_id_idx ON auth_event (person_id, created_at);
+CREATE INDEX IF NOT EXISTS auth_event_created_at_idx ON auth_event (created_at);
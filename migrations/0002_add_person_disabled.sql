@@ -0,0 +1,2 @@
+-- Accounts that recruiters have disabled can't log in.
+ALTER TABLE person ADD COLUMN IF NOT EXISTS disabled boolean NOT NULL DEFAULT false;
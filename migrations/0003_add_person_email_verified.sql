@@ -0,0 +1,3 @@
+-- Registered applicants must verify their e-mail address before they can log in.
+-- Users that existed before registration was added are considered verified.
+ALTER TABLE person ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT true;
@@ -0,0 +1,13 @@
+-- Login sessions, which users can list and revoke.
+CREATE TABLE IF NOT EXISTS auth_session (
+	session_id character varying(64) PRIMARY KEY,
+	person_id bigint NOT NULL,
+	device character varying(255),
+	ip character varying(64),
+	created_at timestamp with time zone NOT NULL,
+	last_seen_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone NOT NULL,
+	revoked_at timestamp with time zone
+);
+
+CREATE INDEX IF NOT EXISTS auth_session_person_id_idx ON auth_session (person_id, expires_at);
@@ -13,16 +13,60 @@ type ResetTokenResponse struct {
 	Token string `json:"reset_token"`
 }
 
+const (
+	// Rule of a field that has the wrong JSON type. Other rules are named after go-playground/validator tags.
+	FieldRuleType = "type"
+	// Rule of a field whose value already belongs to another user.
+	FieldRuleUnique = "unique"
+)
+
+// FieldError describes a request parameter that is missing or invalid.
+type FieldError struct {
+	// Name of the parameter in JSON, forms or query strings
+	Field string `json:"field"`
+	// The rule that the parameter violated, such as "required", "max" or "type"
+	Rule string `json:"rule"`
+	// Parameter of the rule, such as the maximum value or the expected JSON type
+	Param string `json:"param,omitempty"`
+}
+
+// RegistrationResponse is returned when a registration has been received.
+// The response is the same whether or not the e-mail address or personal number already had an account,
+// so that registration can't be used to find out who has an account.
+type RegistrationResponse struct {
+	// The address that the verification link, or a notice about the existing account, was sent to
+	Email string `json:"email"`
+}
+
+// EmailChangeResponse is returned when a confirmation link has been sent to a new e-mail address.
+type EmailChangeResponse struct {
+	// The address that the link was sent to, which replaces the current address once the link is opened
+	Email string `json:"pending_email"`
+}
+
+// CSRFTokenResponse contains a token that must be submitted with forms, together with the matching cookie.
+type CSRFTokenResponse struct {
+	Token string `json:"csrf_token"`
+}
+
 const (
 	// This is a login token.
 	TokenUsageLogin = "login"
 	// This is a reset token.
 	TokenUsageReset = "reset"
+	// This is an e-mail verification token, sent in a link to the user's e-mail address.
+	TokenUsageVerify = "verify"
+	// This is an e-mail change token, sent in a link to the new e-mail address of the user.
+	TokenUsageEmailChange = "email_change"
 )
 
 // CustomClaims represent claims that are specific to this microservice.
 type CustomClaims struct {
 	Usage string `json:"usage"`
+	// ID of the session that a login token belongs to, empty for tokens without a session
+	SessionID string `json:"sid,omitempty"`
+	// The address that an e-mail change token changes the user's e-mail address to
+	NewEmail string `json:"new_email,omitempty"`
 }
 
 // UserClaims are the registered claims for a user's login or reset token.
@@ -31,3 +75,41 @@ type UserClaims struct {
 	User
 	jwt.RegisteredClaims
 }
+
+const (
+	// The service or check is healthy.
+	HealthStatusOK = "ok"
+	// The service or check is not able to serve requests.
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheck is the result of a single readiness check.
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthResponse is returned by the liveness and readiness routes.
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// UserPage is returned when a recruiter lists or searches users.
+type UserPage struct {
+	Users []UserStatus `json:"users"`
+	// Total number of users matching the filter
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// AuthEventPage is returned when a recruiter lists events in the audit trail.
+type AuthEventPage struct {
+	Events []AuthEvent `json:"events"`
+	// Total number of events matching the filter
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
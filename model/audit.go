@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+const (
+	// A user attempted to log in.
+	AuthEventLogin = "login"
+	// A user attempted to reset their password.
+	AuthEventReset = "reset"
+	// A logged in user attempted to change their password.
+	AuthEventPasswordChange = "password_change"
+	// A logged in user attempted to change their e-mail address.
+	AuthEventEmailChange = "email_change"
+	// A login token was issued to a user.
+	AuthEventLoginTokenIssued = "login_token_issued"
+	// A reset token was issued to a user.
+	AuthEventResetTokenIssued = "reset_token_issued"
+	// A link that verifies the e-mail address of a user was mailed to them.
+	AuthEventVerificationSent = "verification_sent"
+	// A recruiter changed the role of a user.
+	AuthEventRoleChange = "role_change"
+	// A recruiter disabled the account of a user.
+	AuthEventAccountDisabled = "account_disabled"
+	// A recruiter enabled the account of a user again.
+	AuthEventAccountEnabled = "account_enabled"
+)
+
+// Represents a security-relevant event in the audit trail.
+type AuthEvent struct {
+	// ID of the event in the database
+	ID int `json:"id"`
+	// What kind of event this is, such as "login"
+	Type string `json:"type"`
+	// ID of the user, if the identity matched a user
+	PersonID *int `json:"person_id,omitempty"`
+	// Identity as submitted by the user
+	Identity string `json:"identity,omitempty"`
+
+	// IP address the request came from
+	IP string `json:"ip"`
+	// User agent of the client that made the request
+	UserAgent string `json:"user_agent"`
+
+	// "success" or the error type that was returned to the user
+	Outcome string `json:"outcome"`
+	// When the event occurred
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Represents a device that a user is logged in on.
+// Every login token belongs to a session, which stops working once the session has been revoked.
+type Session struct {
+	// Random ID of the session, which is embedded in its login tokens
+	ID string `json:"id"`
+	// ID of the user that logged in
+	PersonID int `json:"-"`
+
+	// Browser and operating system of the device, such as "Firefox on Windows"
+	Device string `json:"device"`
+	// IP address the user logged in from
+	IP string `json:"ip"`
+
+	// When the user logged in
+	CreatedAt time.Time `json:"created_at"`
+	// When a request was last made with a token of the session
+	LastSeen time.Time `json:"last_seen"`
+	// When the login token of the session expires
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// The session belongs to the token used to list the sessions
+	Current bool `json:"current"`
+}
+
+// SessionList is returned when a user lists the sessions they're logged in with.
+type SessionList struct {
+	Sessions []Session `json:"sessions"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // Represents the routes that a user is allowed to access.
 type Role int
 
@@ -25,3 +27,32 @@ type User struct {
 	// Bcrypt-encoded password
 	Password string `json:"-"` // Omit from JSON response
 }
+
+// UserStatus is a user as shown to recruiters managing accounts.
+type UserStatus struct {
+	User
+	// First name and surname of the user
+	Name    string `json:"name,omitempty"`
+	Surname string `json:"surname,omitempty"`
+
+	// The user has set a password and can log in without a reset
+	HasPassword bool `json:"has_password"`
+	// The account has been disabled by a recruiter and can't be used to log in
+	Disabled bool `json:"disabled"`
+	// Too many wrong passwords have been entered recently and the account can't be used to log in until LockedUntil
+	Locked      bool       `json:"locked"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// When the user was last issued a login token, if ever
+	LastLogin *time.Time `json:"last_login,omitempty"`
+}
+
+// Registration contains the details of a new applicant.
+type Registration struct {
+	Name    string
+	Surname string
+	// Personal number in the form YYYYMMDDXXXX
+	PersonalNumber string
+	Email          string
+	// Bcrypt-encoded password
+	Password string
+}
@@ -3,26 +3,49 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"strings"
+	"time"
 
+	"github.com/IV1201-Group-2/login-service/config"
 	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/metrics"
 	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tracing"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// A value of 10 matches the cost of the default Spring BCryptPasswordEncoder.
-const passwordCost = 10
+// The bcrypt cost used when hashing new passwords, changed with Configure.
+var passwordCost = config.Default().Auth.PasswordCost
+
+// Configure sets the token expiry periods and password cost used by the service layer.
+// This should be called once on startup, before any requests are handled.
+func Configure(cfg config.Auth) {
+	tokenExpiryPeriod = cfg.TokenExpiry
+	tokenResetExpiryPeriod = cfg.ResetTokenExpiry
+	tokenVerifyExpiryPeriod = cfg.VerifyTokenExpiry
+	passwordCost = cfg.PasswordCost
+}
 
 // Compares a plaintext password with a hashed password stored in the database.
-func ComparePassword(plaintext string, hashed string) bool {
+func ComparePassword(ctx context.Context, plaintext string, hashed string) bool {
+	_, span := tracing.Start(ctx, "service.ComparePassword")
+	defer span.End()
+
+	start := time.Now()
+	defer func() { metrics.BcryptDuration.Observe(time.Since(start).Seconds()) }()
+
 	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plaintext))
 	return err == nil
 }
 
 // Encodes a password for insertion into the database.
-func HashPassword(plaintext string) (string, error) {
+func HashPassword(ctx context.Context, plaintext string) (string, error) {
+	_, span := tracing.Start(ctx, "service.HashPassword")
+
 	result, err := bcrypt.GenerateFromPassword([]byte(plaintext), passwordCost)
+	tracing.End(span, err)
 	if err != nil {
 		return "", ErrBcryptError.Wrap(err)
 	}
@@ -30,7 +53,10 @@ func HashPassword(plaintext string) (string, error) {
 }
 
 // Authenticate a user with the specified identity, password and optionally role.
-func AuthenticateUser(repository *database.UserRepository, identity string, password string, role *model.Role) (*model.User, error) {
+func AuthenticateUser(ctx context.Context, repository *database.UserRepository, identity string, password string, role *model.Role) (user *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "service.AuthenticateUser")
+	defer func() { tracing.End(span, err) }()
+
 	identity = strings.TrimSpace(identity)
 	// Guard against information leak by disallowing empty identity.
 	// This can be the case with empty email for recruiter or empty username for applicant.
@@ -39,8 +65,9 @@ func AuthenticateUser(repository *database.UserRepository, identity string, pass
 	}
 
 	// Query the database for a user with the specified username or email.
-	user, err := repository.Query(identity)
-	if err != nil {
+	user, err = repository.Query(ctx, identity)
+	blocked := accountError(err)
+	if err != nil && blocked == nil {
 		if errors.Is(err, database.ErrUserNotFound) {
 			return nil, ErrWrongIdentity
 		}
@@ -51,29 +78,88 @@ func AuthenticateUser(repository *database.UserRepository, identity string, pass
 	if role != nil && *role != user.Role {
 		return user, ErrWrongIdentity
 	}
-	// Check that user has a valid password in the database
+	// Check that user has a valid password in the database.
+	// Users that can't log in are not allowed to reset their password either.
 	if user.Password == "" {
+		if blocked != nil {
+			return user, blocked
+		}
 		return user, ErrMissingPassword
 	}
 	// Check that the correct password was provided
-	if !ComparePassword(password, user.Password) {
+	if !ComparePassword(ctx, password, user.Password) {
 		return user, ErrWrongPassword
 	}
+	// Only tell the user why they can't log in once they have proven who they are
+	if blocked != nil {
+		return user, blocked
+	}
 
 	return user, nil
 }
 
+// Returns the service error for a user that was found but is not allowed to log in, or nil.
+func accountError(err error) error {
+	switch {
+	case errors.Is(err, database.ErrUserDisabled):
+		return ErrUserDisabled
+	case errors.Is(err, database.ErrEmailUnverified):
+		return ErrEmailUnverified
+	default:
+		return nil
+	}
+}
+
 // Update the password of a user in the database.
-func UpdatePassword(repository *database.UserRepository, token model.UserClaims, password string) error {
+func UpdatePassword(ctx context.Context, repository *database.UserRepository, token model.UserClaims, password string) (err error) {
+	ctx, span := tracing.Start(ctx, "service.UpdatePassword")
+	defer func() { tracing.End(span, err) }()
+
 	// Check if user provided a reset token
 	if token.Usage != model.TokenUsageReset {
 		return ErrWrongUsage
 	}
 
-	hashed, err := HashPassword(password)
+	hashed, err := HashPassword(ctx, password)
 	if err != nil {
 		return err
 	}
 
-	return repository.UpdatePassword(token.User.ID, hashed)
+	return repository.UpdatePassword(ctx, token.User.ID, hashed)
+}
+
+// Change the password of a logged in user after checking their current password.
+// The user is read from the database, so a new token signed for them reflects any changes since they logged in.
+func ChangePassword(ctx context.Context, repository *database.UserRepository, token model.UserClaims, current string, password string) (user *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "service.ChangePassword")
+	defer func() { tracing.End(span, err) }()
+
+	// Reset tokens can only be used to choose a password without knowing the current one
+	if token.Usage != model.TokenUsageLogin {
+		return nil, ErrWrongUsage
+	}
+
+	user, err = repository.Get(ctx, token.User.ID)
+	blocked := accountError(err)
+	if err != nil && blocked == nil {
+		return nil, err
+	}
+	// Check that the correct password was provided
+	if user.Password == "" || !ComparePassword(ctx, current, user.Password) {
+		return user, ErrWrongPassword
+	}
+	if blocked != nil {
+		return user, blocked
+	}
+
+	hashed, err := HashPassword(ctx, password)
+	if err != nil {
+		return user, err
+	}
+	if err = repository.UpdatePassword(ctx, user.ID, hashed); err != nil {
+		return user, err
+	}
+	user.Password = hashed
+
+	return user, nil
 }
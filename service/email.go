@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/mail"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tracing"
+)
+
+// RequestEmailChange checks that a logged in user can change their e-mail address to email.
+// The current password must be provided, so that a stolen token is not enough to take over the account.
+// Returns database.ErrEmailTaken if the address identifies another user.
+// The address is not changed until the link sent by SendEmailChange has been opened.
+func RequestEmailChange(ctx context.Context, repository *database.UserRepository, token model.UserClaims, current string, email string) (user *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "service.RequestEmailChange")
+	defer func() { tracing.End(span, err) }()
+
+	if token.Usage != model.TokenUsageLogin {
+		return nil, ErrWrongUsage
+	}
+
+	user, err = repository.Get(ctx, token.User.ID)
+	blocked := accountError(err)
+	if err != nil && blocked == nil {
+		return nil, err
+	}
+	// Check that the correct password was provided
+	if user.Password == "" || !ComparePassword(ctx, current, user.Password) {
+		return user, ErrWrongPassword
+	}
+	if blocked != nil {
+		return user, blocked
+	}
+
+	if err = repository.EmailAvailable(ctx, user.ID, email); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// SendEmailChange mails a link that changes the e-mail address of the user to email.
+// The link is sent to the new address, and a notice about the change is sent to the current address if the user has one.
+// The token is added to verifyURL as the "token" query parameter.
+func SendEmailChange(ctx context.Context, mailer mail.Mailer, user model.User, email string, signingKey any, verifyURL string) (err error) {
+	ctx, span := tracing.Start(ctx, "service.SendEmailChange")
+	defer func() { tracing.End(span, err) }()
+
+	token, expiry, err := SignEmailChangeToken(ctx, user, email, signingKey)
+	if err != nil {
+		return err
+	}
+	link, err := tokenLink(verifyURL, token)
+	if err != nil {
+		return err
+	}
+
+	err = mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your new e-mail address",
+		Body: fmt.Sprintf("Open this link to use this e-mail address for your account:\n\n%s\n\n"+
+			"The link expires at %s. If you didn't ask to change your e-mail address, you can ignore this e-mail.\n",
+			link, expiry.UTC().Format(time.RFC1123)),
+	})
+	if err != nil || user.Email == "" {
+		return err
+	}
+
+	return mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your e-mail address is being changed",
+		Body: fmt.Sprintf("Someone asked to change the e-mail address of your account to %s. "+
+			"The change takes effect once the link sent to the new address has been opened.\n\n"+
+			"If this wasn't you, change your password to stop further changes.\n", email),
+	})
+}
+
+// SendPasswordReset mails a link that lets the user choose a new password to their own address.
+// The token is added to resetURL as the "token" query parameter.
+// Returns ErrNoEmail if the user doesn't have an e-mail address.
+func SendPasswordReset(ctx context.Context, mailer mail.Mailer, user model.User, signingKey any, resetURL string) (err error) {
+	ctx, span := tracing.Start(ctx, "service.SendPasswordReset")
+	defer func() { tracing.End(span, err) }()
+
+	if user.Email == "" {
+		return ErrNoEmail
+	}
+	token, expiry, err := SignResetToken(ctx, user, signingKey)
+	if err != nil {
+		return err
+	}
+	link, err := tokenLink(resetURL, token)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Choose a new password",
+		Body: fmt.Sprintf("A recruiter has reset the password of your account. Open this link to choose a new password:\n\n%s\n\n"+
+			"The link expires at %s. All devices that were logged in to your account have been logged out.\n",
+			link, expiry.UTC().Format(time.RFC1123)),
+	})
+}
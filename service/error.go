@@ -48,6 +48,12 @@ var (
 
 	// ErrMissingPassword indicates that authentication failed because the user has no password in the database.
 	ErrMissingPassword = &Error{"missing password", nil}
+	// ErrUserDisabled indicates that authentication failed because the account has been disabled.
+	ErrUserDisabled = &Error{"user disabled", nil}
+	// ErrEmailUnverified indicates that authentication failed because the user has not verified their e-mail address.
+	ErrEmailUnverified = &Error{"email not verified", nil}
+	// ErrNoEmail indicates that a link could not be mailed to the user because they don't have an e-mail address.
+	ErrNoEmail = &Error{"no email address", nil}
 	// ErrWrongUsage indicates that password update failed because the token is intended for login.
 	ErrWrongUsage = &Error{"wrong token usage", nil}
 
@@ -1,33 +1,47 @@
 package service
 
 import (
+	"context"
 	"time"
 
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/metrics"
 	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tracing"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Expire tokens after one hour for security.
-const TokenExpiryPeriod = time.Hour
+// Expiry periods of login, reset and verification tokens, changed with Configure.
+// E-mail change tokens are verification tokens for a new address.
+var (
+	tokenExpiryPeriod       = config.Default().Auth.TokenExpiry
+	tokenResetExpiryPeriod  = config.Default().Auth.ResetTokenExpiry
+	tokenVerifyExpiryPeriod = config.Default().Auth.VerifyTokenExpiry
+)
 
-// Expire reset tokens after ten minutes.
-const TokenResetExpiryPeriod = time.Minute * 10
+func signToken(ctx context.Context, claims model.UserClaims, signingKey any) (string, time.Time, error) {
+	_, span := tracing.Start(ctx, "service.SignToken",
+		trace.WithAttributes(attribute.String("token.usage", claims.Usage)))
 
-func signToken(claims jwt.Claims, signingKey any) (string, time.Time, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	expiry, _ := claims.GetExpirationTime()
 
 	encodedToken, err := token.SignedString(signingKey)
+	tracing.End(span, err)
 	if err != nil {
 		return "", time.Now(), ErrJWTError.Wrap(err)
 	}
+	metrics.TokensIssued.WithLabelValues(claims.Usage).Inc()
 
 	return encodedToken, expiry.Time, nil
 }
 
 // Signs a token for the specified user with the specified signing key.
+// The token doesn't belong to a session, so it can't be revoked before it expires. Use StartSession to log in users.
 // This function returns the encoded token in plaintext or an error if signing failed.
-func SignUserToken(user model.User, signingKey any) (string, time.Time, error) {
+func SignUserToken(ctx context.Context, user model.User, signingKey any) (string, time.Time, error) {
 	claims := model.UserClaims{
 		CustomClaims: model.CustomClaims{
 			Usage: model.TokenUsageLogin,
@@ -35,17 +49,34 @@ func SignUserToken(user model.User, signingKey any) (string, time.Time, error) {
 		User: user,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExpiryPeriod)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExpiryPeriod)),
+		},
+	}
+	return signToken(ctx, claims, signingKey)
+}
+
+// Signs a token for the session of a user with the specified signing key.
+// The token expires together with the session.
+func signSessionToken(ctx context.Context, user model.User, session model.Session, signingKey any) (string, time.Time, error) {
+	claims := model.UserClaims{
+		CustomClaims: model.CustomClaims{
+			Usage:     model.TokenUsageLogin,
+			SessionID: session.ID,
+		},
+		User: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(session.CreatedAt),
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
 		},
 	}
-	return signToken(claims, signingKey)
+	return signToken(ctx, claims, signingKey)
 }
 
 // Signs a reset token for the specified user with the specified signing key.
 // The reset token should be sent to the user through a secure channel (such as email)
 // since it grants temporary access to an account without a password.
 // This function returns the encoded token in plaintext or an error if signing failed.
-func SignResetToken(user model.User, signingKey any) (string, time.Time, error) {
+func SignResetToken(ctx context.Context, user model.User, signingKey any) (string, time.Time, error) {
 	claims := model.UserClaims{
 		CustomClaims: model.CustomClaims{
 			Usage: model.TokenUsageReset,
@@ -53,8 +84,59 @@ func SignResetToken(user model.User, signingKey any) (string, time.Time, error)
 		User: user,
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenResetExpiryPeriod)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenResetExpiryPeriod)),
 		},
 	}
-	return signToken(claims, signingKey)
+	return signToken(ctx, claims, signingKey)
+}
+
+// Signs a token that verifies the e-mail address of the specified user with the specified signing key.
+// The token should only be sent to the address in user.Email.
+// This function returns the encoded token in plaintext or an error if signing failed.
+func SignVerifyToken(ctx context.Context, user model.User, signingKey any) (string, time.Time, error) {
+	claims := model.UserClaims{
+		CustomClaims: model.CustomClaims{
+			Usage: model.TokenUsageVerify,
+		},
+		User: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenVerifyExpiryPeriod)),
+		},
+	}
+	return signToken(ctx, claims, signingKey)
+}
+
+// Signs a token that changes the e-mail address of the specified user to email with the specified signing key.
+// The token should only be sent to the new address, which proves that the user controls it.
+// This function returns the encoded token in plaintext or an error if signing failed.
+func SignEmailChangeToken(ctx context.Context, user model.User, email string, signingKey any) (string, time.Time, error) {
+	claims := model.UserClaims{
+		CustomClaims: model.CustomClaims{
+			Usage:    model.TokenUsageEmailChange,
+			NewEmail: email,
+		},
+		User: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenVerifyExpiryPeriod)),
+		},
+	}
+	return signToken(ctx, claims, signingKey)
+}
+
+// Parses a token that was not sent in the Authorization header, such as a token in a link.
+// Returns ErrJWTError if the token is invalid or expired.
+func ParseToken(ctx context.Context, token string, signingKey any) (*model.UserClaims, error) {
+	_, span := tracing.Start(ctx, "service.ParseToken")
+
+	claims := &model.UserClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(_ *jwt.Token) (any, error) {
+		return signingKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, ErrJWTError.Wrap(err)
+	}
+	return claims, nil
 }
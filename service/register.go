@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/mail"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tracing"
+)
+
+// Matches a personal number with a four-digit year, with or without a separator before the last four digits.
+var personalNumberPattern = regexp.MustCompile(`^(\d{8})[-+]?(\d{4})$`)
+
+// NormalizePersonalNumber checks a Swedish personal number in the form YYYYMMDD-XXXX or YYYYMMDDXXXX
+// and returns it in the form YYYYMMDDXXXX. Coordination numbers, where 60 is added to the day, are also accepted.
+// The last digit must be the Luhn checksum of the ten digits after the century.
+func NormalizePersonalNumber(number string) (string, bool) {
+	match := personalNumberPattern.FindStringSubmatch(strings.TrimSpace(number))
+	if match == nil {
+		return "", false
+	}
+	normalized := match[1] + match[2]
+
+	date := normalized[:8]
+	if day, _ := strconv.Atoi(date[6:]); day > 60 {
+		date = fmt.Sprintf("%s%02d", date[:6], day-60)
+	}
+	if _, err := time.Parse("20060102", date); err != nil {
+		return "", false
+	}
+
+	// Luhn algorithm: double every other digit starting with the first
+	sum := 0
+	for i, digit := range normalized[2:] {
+		value := int(digit - '0')
+		if i%2 == 0 {
+			value *= 2
+			if value > 9 {
+				value -= 9
+			}
+		}
+		sum += value
+	}
+	if sum%10 != 0 {
+		return "", false
+	}
+	return normalized, true
+}
+
+// Register an applicant with an unverified e-mail address.
+// The registration must contain the plaintext password, which is hashed before the user is created.
+// Returns database.ErrEmailTaken or database.ErrPersonalNumberTaken if another user has the same identity.
+func Register(ctx context.Context, repository *database.UserRepository, registration model.Registration) (user *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "service.Register")
+	defer func() { tracing.End(span, err) }()
+
+	registration.Password, err = HashPassword(ctx, registration.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := repository.Create(ctx, registration)
+	if err != nil {
+		return nil, err
+	}
+	return &model.User{ID: id, Role: model.RoleApplicant, Email: registration.Email}, nil
+}
+
+// Adds a token to verifyURL as the "token" query parameter.
+func tokenLink(verifyURL string, token string) (*url.URL, error) {
+	link, err := url.Parse(verifyURL)
+	if err != nil {
+		return nil, err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link, nil
+}
+
+// SendRegistrationNotice tells the owner of email that someone tried to register an account
+// with that address or with a personal number that already has an account.
+// It is sent instead of a verification link, so that the response to the registration doesn't reveal who has an account.
+func SendRegistrationNotice(ctx context.Context, mailer mail.Mailer, email string) (err error) {
+	ctx, span := tracing.Start(ctx, "service.SendRegistrationNotice")
+	defer func() { tracing.End(span, err) }()
+
+	return mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "You already have an account",
+		Body: "Someone tried to register an account with this e-mail address, or with a personal number that already has an account.\n\n" +
+			"If this was you, log in to your existing account instead. If you have forgotten your password, ask a recruiter to reset it.\n" +
+			"If this wasn't you, you can ignore this e-mail.\n",
+	})
+}
+
+// SendVerification mails a link that verifies user.Email to that address.
+// The token is added to verifyURL as the "token" query parameter.
+func SendVerification(ctx context.Context, mailer mail.Mailer, user model.User, signingKey any, verifyURL string) (err error) {
+	ctx, span := tracing.Start(ctx, "service.SendVerification")
+	defer func() { tracing.End(span, err) }()
+
+	token, expiry, err := SignVerifyToken(ctx, user, signingKey)
+	if err != nil {
+		return err
+	}
+	link, err := tokenLink(verifyURL, token)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your e-mail address",
+		Body: fmt.Sprintf("Open this link to verify your e-mail address and activate your account:\n\n%s\n\n"+
+			"The link expires at %s. If you didn't create an account, you can ignore this e-mail.\n",
+			link, expiry.UTC().Format(time.RFC1123)),
+	})
+}
+
+// VerifyEmail marks the e-mail address in a verification token as verified,
+// or changes the e-mail address of the user to the one in an e-mail change token.
+// Returns the user with their verified address, or database.ErrUserNotFound if the user no longer has the address in the token.
+// Returns database.ErrEmailTaken if another user has taken the new address of an e-mail change token,
+// or ErrUserDisabled if the account has been disabled since the link was sent.
+func VerifyEmail(ctx context.Context, repository *database.UserRepository, token model.UserClaims) (user *model.User, err error) {
+	ctx, span := tracing.Start(ctx, "service.VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+
+	if token.Usage != model.TokenUsageVerify && token.Usage != model.TokenUsageEmailChange {
+		return nil, ErrWrongUsage
+	}
+	disabled, err := repository.Disabled(ctx, token.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		return nil, ErrUserDisabled
+	}
+
+	user = &token.User
+	switch token.Usage {
+	case model.TokenUsageVerify:
+		err = repository.VerifyEmail(ctx, user.ID, user.Email)
+	case model.TokenUsageEmailChange:
+		err = repository.ChangeEmail(ctx, user.ID, user.Email, token.NewEmail)
+		user.Email = token.NewEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
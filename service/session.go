@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tracing"
+)
+
+// Substrings of user agents and the names they're shown as, checked in order.
+// Browsers based on Chrome also mention Chrome and Safari, so they're checked first.
+var (
+	userAgentBrowsers = [][2]string{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"SamsungBrowser/", "Samsung Internet"},
+		{"Firefox/", "Firefox"}, {"FxiOS/", "Firefox"}, {"CriOS/", "Chrome"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	userAgentSystems = [][2]string{
+		{"Windows", "Windows"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Android", "Android"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	}
+)
+
+// DeviceLabel returns a short description of the device that sent a user agent, such as "Firefox on Windows".
+func DeviceLabel(userAgent string) string {
+	var browser, system string
+	for _, candidate := range userAgentBrowsers {
+		if strings.Contains(userAgent, candidate[0]) {
+			browser = candidate[1]
+			break
+		}
+	}
+	for _, candidate := range userAgentSystems {
+		if strings.Contains(userAgent, candidate[0]) {
+			system = candidate[1]
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+// StartSession logs a user in on the device that sent userAgent from ip.
+// This function returns a login token that belongs to the new session or an error if the session couldn't be created.
+func StartSession(ctx context.Context, repository *database.SessionRepository, user model.User, userAgent string, ip string, signingKey any) (token string, expiry time.Time, err error) {
+	ctx, span := tracing.Start(ctx, "service.StartSession")
+	defer func() { tracing.End(span, err) }()
+
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return "", time.Now(), err
+	}
+	// Database and token timestamps have a precision of one second
+	now := time.Now().Truncate(time.Second)
+	session := model.Session{
+		ID:        hex.EncodeToString(id),
+		PersonID:  user.ID,
+		Device:    DeviceLabel(userAgent),
+		IP:        ip,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(tokenExpiryPeriod),
+	}
+
+	if err = repository.Create(ctx, session); err != nil {
+		return "", time.Now(), err
+	}
+	return signSessionToken(ctx, user, session, signingKey)
+}
+
+// ListSessions returns the active sessions of a logged in user, with the session of their token marked as current.
+func ListSessions(ctx context.Context, repository *database.SessionRepository, token model.UserClaims) (sessions []model.Session, err error) {
+	ctx, span := tracing.Start(ctx, "service.ListSessions")
+	defer func() { tracing.End(span, err) }()
+
+	if token.Usage != model.TokenUsageLogin {
+		return nil, ErrWrongUsage
+	}
+
+	sessions, err = repository.List(ctx, token.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == token.SessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs a user out of one of their sessions, which can be the session of their own token.
+// Returns database.ErrSessionNotFound if the user has no active session with that ID.
+func RevokeSession(ctx context.Context, repository *database.SessionRepository, token model.UserClaims, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "service.RevokeSession")
+	defer func() { tracing.End(span, err) }()
+
+	if token.Usage != model.TokenUsageLogin {
+		return ErrWrongUsage
+	}
+	return repository.Revoke(ctx, token.User.ID, id)
+}
@@ -0,0 +1,161 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that login attempts are recorded and can be listed by a recruiter.
+func TestAuditLogin(t *testing.T) {
+	t.Parallel()
+
+	identity := "audit-" + tests.RandomStr(16) + "@example.com"
+	res := tests.Request(t, "/api/login", map[string]any{
+		"identity": identity,
+		"password": tests.MockPassword,
+	}, map[string]string{
+		"User-Agent": "audit-test",
+	})
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	recruiterToken, _, _ := service.SignUserToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+	res = tests.GetRequest(t, "/api/audit?identity="+url.QueryEscape(identity), map[string]string{
+		"Authorization": "Bearer " + recruiterToken,
+	})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.AuthEventPage{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, 1, obj.Total)
+	require.Len(t, obj.Events, 1)
+	require.Equal(t, model.AuthEventLogin, obj.Events[0].Type)
+	require.Equal(t, identity, obj.Events[0].Identity)
+	require.Equal(t, "WRONG_IDENTITY", obj.Events[0].Outcome)
+	require.Equal(t, "audit-test", obj.Events[0].UserAgent)
+	require.Nil(t, obj.Events[0].PersonID)
+}
+
+// Tests that values longer than the columns of the audit trail are cut off instead of losing the event.
+func TestAuditLongValues(t *testing.T) {
+	t.Parallel()
+
+	identity := "audit-" + tests.RandomStr(300) + "@example.com"
+	userAgent := "audit-test/" + tests.RandomStr(1000)
+	realIP := "192.0.2." + tests.RandomStr(100)
+	res := tests.Request(t, "/api/login", map[string]any{
+		"identity": identity,
+		"password": tests.MockPassword,
+	}, map[string]string{
+		"User-Agent": userAgent,
+		"X-Real-IP":  realIP,
+	})
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	recruiterToken, _, _ := service.SignUserToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+	res = tests.GetRequest(t, "/api/audit?identity="+url.QueryEscape(identity[:255]), map[string]string{
+		"Authorization": "Bearer " + recruiterToken,
+	})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.AuthEventPage{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Len(t, obj.Events, 1)
+	require.Equal(t, userAgent[:512], obj.Events[0].UserAgent)
+	require.Equal(t, realIP[:64], obj.Events[0].IP)
+}
+
+// Tests that token issuance is recorded with the ID of the user.
+func TestAuditTokenIssued(t *testing.T) {
+	t.Parallel()
+
+	res := tests.Request(t, "/api/login", map[string]any{
+		"identity": tests.MockRecruiter.Username,
+		"password": tests.MockPassword,
+	}, map[string]string{})
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	recruiterToken, _, _ := service.SignUserToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+	res = tests.GetRequest(t, "/api/audit?type=login_token_issued&limit=1&person_id="+strconv.Itoa(tests.MockRecruiter.ID), map[string]string{
+		"Authorization": "Bearer " + recruiterToken,
+	})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.AuthEventPage{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Len(t, obj.Events, 1)
+	require.Equal(t, 1, obj.Limit)
+	require.Equal(t, tests.MockRecruiter.ID, *obj.Events[0].PersonID)
+	require.Equal(t, tests.MockRecruiter.Username, obj.Events[0].Identity)
+}
+
+// Tests that only recruiters can list the audit trail.
+func TestAuditForbidden(t *testing.T) {
+	t.Parallel()
+
+	applicantToken, _, _ := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
+	resetToken, _, _ := service.SignResetToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+
+	cases := map[string]struct {
+		headers map[string]string
+		status  int
+		errType string
+	}{
+		"no token":        {map[string]string{}, http.StatusUnauthorized, "TOKEN_NOT_PROVIDED"},
+		"applicant token": {map[string]string{"Authorization": "Bearer " + applicantToken}, http.StatusForbidden, "FORBIDDEN"},
+		"reset token":     {map[string]string{"Authorization": "Bearer " + resetToken}, http.StatusUnauthorized, "INVALID_TOKEN"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			res := tests.GetRequest(t, "/api/audit", tc.headers)
+			defer res.Body.Close()
+
+			require.Equal(t, tc.status, res.StatusCode)
+
+			obj := api.Error{}
+			body, _ := io.ReadAll(res.Body)
+
+			require.NoError(t, json.Unmarshal(body, &obj))
+			require.Equal(t, tc.errType, obj.ErrorType)
+		})
+	}
+}
+
+// Tests that the audit trail rejects invalid filters.
+func TestAuditInvalidFilter(t *testing.T) {
+	t.Parallel()
+
+	recruiterToken, _, _ := service.SignUserToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+	res := tests.GetRequest(t, "/api/audit?limit=1000", map[string]string{
+		"Authorization": "Bearer " + recruiterToken,
+	})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -0,0 +1,129 @@
+package api_test
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Creates a server that allows one origin and all subdomains of another.
+func corsServer(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	cfg := *tests.Config
+	cfg.CORS.AllowOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	cfg.CORS.AllowCredentials = true
+	cfg.CORS.MaxAge = 5 * time.Minute
+
+	srv, _, err := api.NewServer(db, &cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// Tests that preflight requests are only answered with CORS headers for allowed origins.
+func TestCORSPreflight(t *testing.T) {
+	t.Parallel()
+
+	srv := corsServer(t)
+
+	cases := map[string]struct {
+		origin  string
+		allowed bool
+	}{
+		"exact origin":       {"https://app.example.com", true},
+		"subdomain":          {"https://login.example.org", true},
+		"nested subdomain":   {"https://a.b.example.org", true},
+		"wildcard apex":      {"https://example.org", false},
+		"wrong scheme":       {"http://app.example.com", false},
+		"unknown origin":     {"https://evil.example.net", false},
+		"suffix of allowed":  {"https://evilexample.org", false},
+		"allowed as prefix":  {"https://app.example.com.evil.net", false},
+		"subdomain of exact": {"https://x.app.example.com", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/api/login", nil)
+			req.Header.Set(echo.HeaderOrigin, tc.origin)
+			req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+			req.Header.Set(echo.HeaderAccessControlRequestHeaders, "Content-Type")
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusNoContent, rec.Code)
+			if !tc.allowed {
+				require.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+				require.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+				return
+			}
+
+			require.Equal(t, tc.origin, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+			require.Equal(t, "true", rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+			require.Equal(t, "GET,POST", rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+			require.Equal(t, "Authorization,Content-Type,X-Request-ID", rec.Header().Get(echo.HeaderAccessControlAllowHeaders))
+			require.Equal(t, "300", rec.Header().Get(echo.HeaderAccessControlMaxAge))
+		})
+	}
+}
+
+// Tests that actual requests are handled, but only allowed origins can read the response.
+func TestCORSRequest(t *testing.T) {
+	t.Parallel()
+
+	srv := corsServer(t)
+
+	cases := map[string]struct {
+		origin  string
+		allowed bool
+	}{
+		"allowed origin":    {"https://app.example.com", true},
+		"allowed subdomain": {"https://login.example.org", true},
+		"denied origin":     {"https://evil.example.net", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			res := tests.CustomGetRequest(t, srv, "/healthz", map[string]string{echo.HeaderOrigin: tc.origin})
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			if tc.allowed {
+				require.Equal(t, tc.origin, res.Header.Get(echo.HeaderAccessControlAllowOrigin))
+				require.Equal(t, "true", res.Header.Get(echo.HeaderAccessControlAllowCredentials))
+			} else {
+				require.Empty(t, res.Header.Get(echo.HeaderAccessControlAllowOrigin))
+			}
+		})
+	}
+}
+
+// Tests that cross-origin requests are denied by default.
+func TestCORSDefaultDeny(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	srv, _, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	res := tests.CustomGetRequest(t, srv, "/healthz", map[string]string{echo.HeaderOrigin: "https://app.example.com"})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Empty(t, res.Header.Get(echo.HeaderAccessControlAllowOrigin))
+}
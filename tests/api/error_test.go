@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/database"
 	"github.com/IV1201-Group-2/login-service/tests"
 	// Imports ChaiSQL driver.
 	_ "github.com/chaisql/chai/driver"
@@ -94,7 +95,7 @@ func TestDatabaseConnectionDown(t *testing.T) {
 	db, err := sql.Open("chai", ":memory:")
 	require.NoError(t, err)
 
-	srv, err := api.NewServer(db)
+	srv, _, err := api.NewServer(db, tests.Config)
 	require.NoError(t, err)
 	defer srv.Close()
 
@@ -109,7 +110,46 @@ func TestDatabaseConnectionDown(t *testing.T) {
 	}, map[string]string{})
 	defer res.Body.Close()
 
-	require.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+	obj := api.Error{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, "SERVICE_UNAVAILABLE", obj.ErrorType)
+}
+
+// Tests that the server fails fast with Retry-After once the database circuit breaker opens.
+func TestDatabaseCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open(tests.UnreachableDriver, "")
+	require.NoError(t, err)
+	defer database.Close(db)
+
+	srv, _, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	params := map[string]any{
+		"identity": tests.MockApplicant.Email,
+		"password": tests.MockPassword,
+	}
+	// The first failure should not open the circuit
+	res := tests.CustomRequest(t, srv, "/api/login", params, map[string]string{})
+	res.Body.Close()
+	require.Empty(t, res.Header.Get("Retry-After"))
+
+	for i := 1; i < database.BreakerThreshold; i++ {
+		res := tests.CustomRequest(t, srv, "/api/login", params, map[string]string{})
+		res.Body.Close()
+	}
+
+	res = tests.CustomRequest(t, srv, "/api/login", params, map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	require.NotEmpty(t, res.Header.Get("Retry-After"))
 
 	obj := api.Error{}
 	body, _ := io.ReadAll(res.Body)
@@ -0,0 +1,76 @@
+package api_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the liveness probe responds without a token.
+func TestLiveness(t *testing.T) {
+	t.Parallel()
+
+	res := tests.GetRequest(t, "/healthz", map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.HealthResponse{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, model.HealthStatusOK, obj.Status)
+}
+
+// Tests that the readiness probe reports every check when the service is ready.
+func TestReadiness(t *testing.T) {
+	t.Parallel()
+
+	res := tests.GetRequest(t, "/readyz", map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.HealthResponse{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, model.HealthStatusOK, obj.Status)
+	require.Equal(t, model.HealthStatusOK, obj.Checks["database"].Status)
+	require.Equal(t, model.HealthStatusOK, obj.Checks["signing_key"].Status)
+}
+
+// Tests that the readiness probe fails when the database is down.
+func TestReadinessDatabaseDown(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+
+	srv, _, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	require.NoError(t, db.Close())
+
+	res := tests.CustomGetRequest(t, srv, "/readyz", map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+
+	obj := model.HealthResponse{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, model.HealthStatusUnavailable, obj.Status)
+	require.Equal(t, model.HealthStatusUnavailable, obj.Checks["database"].Status)
+	require.NotEmpty(t, obj.Checks["database"].Error)
+	require.Equal(t, model.HealthStatusOK, obj.Checks["signing_key"].Status)
+}
@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that wrong passwords at login, password change and e-mail change together lock the account on the address they came from.
+func TestLockout(t *testing.T) {
+	t.Parallel()
+
+	cfg := *tests.Config
+	cfg.Auth.MaxFailedAttempts = 3
+	srv, _, err := api.NewServer(tests.Database, &cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	loginToken, _, err := service.SignUserToken(context.Background(), tests.MockApplicant10, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	headers := map[string]string{"Authorization": "Bearer " + loginToken}
+	login := map[string]any{"identity": tests.MockApplicant10.Email, "password": "wrong"}
+
+	res := tests.CustomRequest(t, srv, "/api/login", login, nil)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, "WRONG_IDENTITY", errorType(t, res))
+
+	res = tests.CustomRequest(t, srv, "/api/password", map[string]any{"current_password": "wrong", "password": "newpassword"}, headers)
+	require.Equal(t, "WRONG_IDENTITY", errorType(t, res))
+
+	res = tests.CustomRequest(t, srv, "/api/email", map[string]any{"current_password": "wrong", "email": "mockuser-locked@example.com"}, headers)
+	require.Equal(t, "WRONG_IDENTITY", errorType(t, res))
+
+	// The correct password is refused as well until the attempts are older than the lockout period
+	login["password"] = tests.MockPassword
+	res = tests.CustomRequest(t, srv, "/api/login", login, nil)
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Positive(t, retryAfter)
+	require.LessOrEqual(t, retryAfter, int(cfg.Auth.LockoutPeriod.Seconds()))
+	require.Equal(t, "ACCOUNT_LOCKED", errorType(t, res))
+
+	res = tests.CustomRequest(t, srv, "/api/password", map[string]any{"current_password": tests.MockPassword, "password": "newpassword"}, headers)
+	require.Equal(t, "ACCOUNT_LOCKED", errorType(t, res))
+
+	// Guessing from one address doesn't lock the user out on other addresses
+	res = tests.CustomRequest(t, srv, "/api/login", login, map[string]string{"X-Real-IP": "198.51.100.7"})
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Recruiters can see that the account is locked on some address
+	res = tests.CustomGetRequest(t, srv, "/api/users/"+strconv.Itoa(tests.MockApplicant10.ID), recruiterHeaders(t))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	status := model.UserStatus{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &status))
+	require.True(t, status.Locked)
+	require.NotNil(t, status.LockedUntil)
+	require.WithinDuration(t, time.Now().Add(cfg.Auth.LockoutPeriod), *status.LockedUntil, time.Minute)
+
+	// Wrong current passwords are recorded as the route they were entered at, not as logins
+	events, _, err := database.NewAuditRepository(tests.Database).List(context.Background(), database.AuditFilter{
+		PersonID: &tests.MockApplicant10.ID,
+		Outcome:  "WRONG_IDENTITY",
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	require.ElementsMatch(t, []string{model.AuthEventLogin, model.AuthEventPasswordChange, model.AuthEventEmailChange}, types)
+}
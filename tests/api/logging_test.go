@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the server generates a request ID if the client did not provide one.
+func TestRequestIDGenerated(t *testing.T) {
+	t.Parallel()
+
+	res := tests.GetRequest(t, "/healthz", map[string]string{})
+	defer res.Body.Close()
+
+	require.NotEmpty(t, res.Header.Get(echo.HeaderXRequestID))
+}
+
+// Tests that the server echoes back a request ID provided by the client.
+func TestRequestIDHonoured(t *testing.T) {
+	t.Parallel()
+
+	res := tests.GetRequest(t, "/healthz", map[string]string{
+		echo.HeaderXRequestID: "test-request-id",
+	})
+	defer res.Body.Close()
+
+	require.Equal(t, "test-request-id", res.Header.Get(echo.HeaderXRequestID))
+}
+
+// Tests that the server replaces a request ID that could be used to inject content into the logs.
+func TestRequestIDInvalid(t *testing.T) {
+	t.Parallel()
+
+	res := tests.GetRequest(t, "/healthz", map[string]string{
+		echo.HeaderXRequestID: "test request=id",
+	})
+	defer res.Body.Close()
+
+	require.NotEmpty(t, res.Header.Get(echo.HeaderXRequestID))
+	require.NotEqual(t, "test request=id", res.Header.Get(echo.HeaderXRequestID))
+}
+
+// Tests that JSON log lines carry request-scoped fields.
+// This test can't run in parallel since it changes the global log output.
+func TestJSONLogFields(t *testing.T) {
+	var buf bytes.Buffer
+	logging.SetOutput(&buf)
+	require.NoError(t, logging.SetFormat(logging.FormatJSON))
+	defer func() {
+		logging.SetOutput(os.Stdout)
+		require.NoError(t, logging.SetFormat(logging.FormatText))
+	}()
+
+	res := tests.Request(t, "/api/login", map[string]any{
+		"identity": tests.MockApplicant.Email,
+		"password": tests.MockPassword,
+	}, map[string]string{
+		echo.HeaderXRequestID: "test-json-logs",
+	})
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := map[string]any{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		if line[logging.FieldRequestID] == "test-json-logs" {
+			lines = append(lines, line)
+		}
+	}
+	require.NotEmpty(t, lines)
+
+	for _, line := range lines {
+		require.Equal(t, "/api/login", line[logging.FieldRoute])
+		require.InDelta(t, tests.MockApplicant.ID, line[logging.FieldUserID], 0)
+	}
+
+	// The last line is written by the request logger once the response is complete
+	last := lines[len(lines)-1]
+	require.InDelta(t, http.StatusOK, last[logging.FieldStatus], 0)
+	require.Contains(t, last, logging.FieldLatency)
+}
+
+// Tests that tokens in query strings, such as e-mail verification links, are not logged.
+// This test can't run in parallel since it changes the global log output.
+func TestQueryTokenNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logging.SetOutput(&buf)
+	defer logging.SetOutput(os.Stdout)
+
+	res := tests.GetRequest(t, "/api/verify?token=secret-token-value", map[string]string{})
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	require.NotContains(t, buf.String(), "secret-token-value")
+	require.Contains(t, buf.String(), "/api/verify?token=REDACTED")
+}
@@ -1,10 +1,10 @@
 package api_test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
-	"os"
 	"testing"
 
 	"github.com/IV1201-Group-2/login-service/api"
@@ -173,7 +173,7 @@ func TestLoginMissingPassword(t *testing.T) {
 func TestAlreadyLoggedIn(t *testing.T) {
 	t.Parallel()
 
-	testToken, _, _ := service.SignUserToken(tests.MockApplicant, []byte(os.Getenv("JWT_SECRET")))
+	testToken, _, _ := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
 
 	res := tests.Request(t, "/api/login", map[string]any{
 		"identity": tests.MockApplicant.Email,
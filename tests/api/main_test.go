@@ -26,5 +26,5 @@ func TestMain(m *testing.M) {
 }
 
 func mockKeyFunc(_ *jwt.Token) (any, error) {
-	return []byte(os.Getenv("JWT_SECRET")), nil
+	return []byte(tests.MockSecret), nil
 }
@@ -0,0 +1,119 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Every error that can be returned to users.
+var userVisibleErrors = []*api.Error{
+	api.ErrUnknown,
+	api.ErrServiceUnavailable,
+	api.ErrMissingParameters,
+	api.ErrMalformedRequest,
+	api.ErrMissingPassword,
+	api.ErrMissingEmail,
+	api.ErrWrongIdentity,
+	api.ErrAccountDisabled,
+	api.ErrAccountLocked,
+	api.ErrEmailNotVerified,
+	api.ErrIdentityTaken,
+	api.ErrAlreadyLoggedIn,
+	api.ErrTokenNotProvided,
+	api.ErrTokenInvalid,
+	api.ErrUserNotFound,
+	api.ErrSessionNotFound,
+	api.ErrForbidden,
+	api.ErrCSRFFailed,
+	api.ErrFeatureDisabled,
+	api.ErrInvalidRoute,
+	api.ErrHTTPSRequired,
+}
+
+// Tests that every error has a message in every supported language.
+func TestMessageCatalogs(t *testing.T) {
+	t.Parallel()
+
+	for _, err := range userVisibleErrors {
+		english, lang := api.LocalizedMessage(err, "en")
+		require.Equal(t, "en", lang)
+		require.NotEmpty(t, english.Title, err.ErrorType)
+		require.NotEmpty(t, english.Message, err.ErrorType)
+
+		swedish, lang := api.LocalizedMessage(err, "sv")
+		require.Equal(t, "sv", lang, err.ErrorType)
+		require.NotEmpty(t, swedish.Title, err.ErrorType)
+		require.NotEqual(t, english.Message, swedish.Message, err.ErrorType)
+	}
+}
+
+// Tests that the language is chosen from the Accept-Language header with a fallback to English.
+func TestMessageLanguage(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"":                          "en",
+		"sv":                        "sv",
+		"sv-SE":                     "sv",
+		"sv-SE,sv;q=0.9,en;q=0.8":   "sv",
+		"en-GB,en;q=0.9,sv;q=0.8":   "en",
+		"de":                        "en",
+		"de,sv;q=0.5":               "sv",
+		"*":                         "en",
+		"not a ; valid = header,,,": "en",
+	}
+
+	for header, expected := range cases {
+		_, lang := api.LocalizedMessage(api.ErrWrongIdentity, header)
+		require.Equal(t, expected, lang, "Accept-Language: %s", header)
+	}
+
+	// Unknown error types fall back to the status text
+	message, _ := api.LocalizedMessage(&api.Error{StatusCode: http.StatusTeapot, ErrorType: "TEAPOT"}, "sv")
+	require.Equal(t, http.StatusText(http.StatusTeapot), message.Title)
+}
+
+// Tests that errors returned by the server contain a message in the user's language.
+func TestLocalizedErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := offlineServer(t)
+	swedish, _ := api.LocalizedMessage(api.ErrMissingParameters, "sv")
+
+	res := tests.CustomRequest(t, srv, "/api/login", map[string]any{}, map[string]string{"Accept-Language": "sv-SE"})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	require.Equal(t, "sv", res.Header.Get("Content-Language"))
+	require.Contains(t, res.Header.Values(echo.HeaderVary), "Accept-Language")
+
+	obj := api.Error{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, api.ErrMissingParameters.ErrorType, obj.ErrorType)
+	require.Equal(t, swedish.Message, obj.Message)
+
+	res = tests.CustomRequest(t, srv, "/api/v2/login", map[string]any{}, map[string]string{"Accept-Language": "sv"})
+	defer res.Body.Close()
+
+	problem := readProblem(t, res)
+	require.Equal(t, swedish.Title, problem.Title)
+	require.Equal(t, swedish.Message, problem.Detail)
+
+	// English is used if no language is requested
+	res = tests.CustomRequest(t, srv, "/api/login", map[string]any{}, map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, "en", res.Header.Get("Content-Language"))
+	english, _ := api.LocalizedMessage(api.ErrMissingParameters, "")
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, english.Message, obj.Message)
+}
@@ -0,0 +1,79 @@
+package api_test
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the server exports metrics for logins in Prometheus format.
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	res := tests.Request(t, "/api/login", map[string]any{
+		"identity": tests.MockApplicant.Email,
+		"password": tests.MockPassword,
+	}, map[string]string{})
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = tests.Request(t, "/api/login", map[string]any{
+		"identity": tests.MockApplicant.Email,
+		"password": "wrong",
+	}, map[string]string{})
+	res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// Requests for routes that don't exist share one label
+	res = tests.GetRequest(t, "/api/no-such-route-"+strconv.Itoa(rand.Int()), map[string]string{})
+	res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	rec := httptest.NewRecorder()
+	api.NewMetricsServer(":9090").Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+
+	metrics := rec.Body.String()
+
+	require.Contains(t, metrics, `login_service_login_attempts_total{outcome="success"}`)
+	require.Contains(t, metrics, `login_service_login_attempts_total{outcome="WRONG_IDENTITY"}`)
+	require.Contains(t, metrics, `login_service_tokens_issued_total{usage="login"}`)
+	require.Contains(t, metrics, `login_service_handler_duration_seconds_count{method="POST",route="/api/login"}`)
+	require.Contains(t, metrics, "login_service_bcrypt_compare_duration_seconds_count")
+	require.Contains(t, metrics, `login_service_handler_duration_seconds_count{method="unmatched",route="unmatched"}`)
+	require.NotContains(t, metrics, "no-such-route")
+	require.Contains(t, metrics, "go_sql_open_connections")
+}
+
+// Tests that metrics are only served on the metrics port and never by the API.
+func TestMetricsPort(t *testing.T) {
+	t.Parallel()
+
+	for _, port := range []string{"", "9090"} {
+		cfg := *tests.Config
+		cfg.MetricsPort = port
+		srv, _, err := api.NewServer(tests.Database, &cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { srv.Close() })
+
+		res := tests.CustomGetRequest(t, srv, "/metrics", map[string]string{})
+		res.Body.Close()
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+	}
+
+	metricsSrv := api.NewMetricsServer(":9090")
+	require.Equal(t, ":9090", metricsSrv.Addr)
+
+	// Nothing but metrics is served on the metrics port
+	rec := httptest.NewRecorder()
+	metricsSrv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
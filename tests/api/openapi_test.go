@@ -0,0 +1,143 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Subset of the OpenAPI document that is checked by tests.
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Paths   map[string]map[string]struct {
+		Security    []map[string][]string `json:"security"`
+		RequestBody *struct {
+			Content map[string]struct {
+				Schema struct {
+					Required   []string       `json:"required"`
+					Properties map[string]any `json:"properties"`
+				} `json:"schema"`
+			} `json:"content"`
+		} `json:"requestBody"`
+		Parameters []struct {
+			Name string `json:"name"`
+			In   string `json:"in"`
+		} `json:"parameters"`
+		Responses map[string]struct {
+			Content map[string]struct {
+				Schema struct {
+					Ref   string `json:"$ref"`
+					AllOf []struct {
+						Properties struct {
+							Error struct {
+								Enum []string `json:"enum"`
+							} `json:"error"`
+						} `json:"properties"`
+					} `json:"allOf"`
+				} `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Required   []string       `json:"required"`
+			Properties map[string]any `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// Starts a server without a database and returns it with its OpenAPI document.
+func fetchOpenAPI(t *testing.T) (*echo.Echo, openAPIDocument) {
+	t.Helper()
+
+	srv := offlineServer(t)
+
+	res := tests.CustomGetRequest(t, srv, "/api/openapi.json", map[string]string{})
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	doc := openAPIDocument{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &doc))
+	return srv, doc
+}
+
+// Tests that every route registered by the server is described in the OpenAPI document and vice versa.
+func TestOpenAPIRoutes(t *testing.T) {
+	t.Parallel()
+
+	srv, doc := fetchOpenAPI(t)
+	require.Equal(t, "3.0.3", doc.OpenAPI)
+
+	registered := map[string]bool{}
+	for _, route := range srv.Routes() {
+		// Echo path parameters such as :id are written as {id} in OpenAPI
+		segments := strings.Split(route.Path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, ":") {
+				segments[i] = "{" + segment[1:] + "}"
+			}
+		}
+		path := strings.Join(segments, "/")
+		registered[route.Method+" "+path] = true
+
+		require.Contains(t, doc.Paths, path, "route %s %s is missing from the OpenAPI document", route.Method, path)
+		require.Contains(t, doc.Paths[path], strings.ToLower(route.Method), "route %s %s is missing from the OpenAPI document", route.Method, path)
+	}
+
+	for path, methods := range doc.Paths {
+		for method := range methods {
+			require.True(t, registered[strings.ToUpper(method)+" "+path], "%s %s is documented but not registered", method, path)
+		}
+	}
+}
+
+// Tests that parameters, responses and errors are generated from the handlers.
+func TestOpenAPIOperations(t *testing.T) {
+	t.Parallel()
+
+	_, doc := fetchOpenAPI(t)
+
+	login := doc.Paths["/api/login"]["post"]
+	require.NotNil(t, login.RequestBody)
+	for _, contentType := range []string{echo.MIMEApplicationJSON, echo.MIMEApplicationForm} {
+		schema := login.RequestBody.Content[contentType].Schema
+		require.ElementsMatch(t, []string{"identity", "password"}, schema.Required)
+		require.Contains(t, schema.Properties, "role")
+	}
+	require.Equal(t, "#/components/schemas/LoginTokenResponse", login.Responses["200"].Content[echo.MIMEApplicationJSON].Schema.Ref)
+	require.Contains(t, login.Responses["401"].Content[echo.MIMEApplicationJSON].Schema.AllOf[1].Properties.Error.Enum, api.ErrWrongIdentity.ErrorType)
+	require.Contains(t, login.Responses["404"].Content[echo.MIMEApplicationJSON].Schema.AllOf[1].Properties.Error.Enum, api.ErrMissingPassword.ErrorType)
+	require.Contains(t, login.Responses["403"].Content[echo.MIMEApplicationJSON].Schema.AllOf[1].Properties.Error.Enum, api.ErrCSRFFailed.ErrorType)
+	require.Empty(t, login.Security)
+
+	reset := doc.Paths["/api/reset"]["post"]
+	require.Equal(t, []string{"password"}, reset.RequestBody.Content[echo.MIMEApplicationJSON].Schema.Required)
+	require.Contains(t, reset.Security[0], "bearerAuth")
+
+	audit := doc.Paths["/api/audit"]["get"]
+	require.Nil(t, audit.RequestBody)
+	names := []string{}
+	for _, param := range audit.Parameters {
+		require.Equal(t, "query", param.In)
+		names = append(names, param.Name)
+	}
+	require.ElementsMatch(t, []string{"person_id", "identity", "type", "outcome", "from", "to", "limit", "offset"}, names)
+
+	// Version 2 routes only return problem details
+	v2Login := doc.Paths["/api/v2/login"]["post"]
+	require.Contains(t, v2Login.Responses["401"].Content, api.MIMEApplicationProblemJSON)
+	require.NotContains(t, v2Login.Responses["401"].Content, echo.MIMEApplicationJSON)
+	require.Contains(t, login.Responses["401"].Content, api.MIMEApplicationProblemJSON)
+
+	require.Contains(t, doc.Components.Schemas["LoginTokenResponse"].Required, "token")
+	require.Contains(t, doc.Components.Schemas["AuthEvent"].Properties, "created_at")
+	require.Contains(t, doc.Components.Schemas["Error"].Required, "error")
+}
@@ -0,0 +1,137 @@
+package api_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Creates a server without a database, for routes that fail before the database is used.
+func offlineServer(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	srv, _, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// Creates a server connected to the test database.
+// Tokens are only accepted if their user exists in the database and has not been disabled.
+func databaseServer(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	srv, _, err := api.NewServer(tests.Database, tests.Config)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// Reads problem details from a response.
+func readProblem(t *testing.T, res *http.Response) api.Problem {
+	t.Helper()
+
+	require.Equal(t, api.MIMEApplicationProblemJSON, res.Header.Get(echo.HeaderContentType))
+
+	obj := api.Problem{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	return obj
+}
+
+// Tests that version 2 routes return errors as problem details.
+func TestProblemV2(t *testing.T) {
+	t.Parallel()
+
+	srv := offlineServer(t)
+
+	res := tests.CustomRequest(t, srv, "/api/v2/login", map[string]any{}, map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	obj := readProblem(t, res)
+	require.Equal(t, api.ErrMissingParameters.ErrorType, obj.ErrorType)
+	require.Equal(t, http.StatusBadRequest, obj.Status)
+	require.Equal(t, "Missing parameters", obj.Title)
+	require.NotEmpty(t, obj.Detail)
+	require.Equal(t, "/api/v2/login", obj.Instance)
+	require.Contains(t, obj.Type, "#missing_parameters")
+
+	res = tests.CustomGetRequest(t, srv, "/api/v2/audit", map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, api.ErrTokenNotProvided.ErrorType, readProblem(t, res).ErrorType)
+}
+
+// Tests that unknown routes under the version 2 prefix return problem details.
+func TestProblemV2WrongRoute(t *testing.T) {
+	t.Parallel()
+
+	srv := offlineServer(t)
+
+	res := tests.CustomRequest(t, srv, "/api/v2/wrong", map[string]any{}, map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	obj := readProblem(t, res)
+	require.Equal(t, api.ErrInvalidRoute.ErrorType, obj.ErrorType)
+	require.Equal(t, "/api/v2/wrong", obj.Instance)
+}
+
+// Tests that version 1 routes keep their error format unless the client accepts problem details.
+func TestProblemNegotiation(t *testing.T) {
+	t.Parallel()
+
+	srv := offlineServer(t)
+
+	cases := map[string]struct {
+		accept  string
+		problem bool
+	}{
+		"no accept header":      {"", false},
+		"json":                  {echo.MIMEApplicationJSON, false},
+		"any":                   {"*/*", false},
+		"problem details":       {api.MIMEApplicationProblemJSON, true},
+		"problem details first": {api.MIMEApplicationProblemJSON + ", application/json;q=0.9", true},
+		"problem details later": {"application/json, application/problem+json;q=0.5", true},
+		"problem details q=0":   {"application/json, application/problem+json;q=0", false},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.accept != "" {
+				headers[echo.HeaderAccept] = tc.accept
+			}
+			res := tests.CustomRequest(t, srv, "/api/login", map[string]any{}, headers)
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusBadRequest, res.StatusCode)
+			if tc.problem {
+				require.Equal(t, api.ErrMissingParameters.ErrorType, readProblem(t, res).ErrorType)
+				return
+			}
+
+			require.Contains(t, res.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+			require.Contains(t, res.Header.Values(echo.HeaderVary), echo.HeaderAccept)
+
+			obj := map[string]any{}
+			body, _ := io.ReadAll(res.Body)
+			require.NoError(t, json.Unmarshal(body, &obj))
+			require.Equal(t, api.ErrMissingParameters.ErrorType, obj["error"])
+			require.NotContains(t, obj, "title")
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that applicants can register and log in once they have verified their e-mail address.
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	params := map[string]any{
+		"name":            "Registered",
+		"surname":         "Applicant",
+		"personal_number": "19880808-8887",
+		"email":           "mockuser-registered@example.com",
+		"password":        "newpassword",
+	}
+	login := map[string]any{"identity": params["email"], "password": params["password"]}
+
+	res := tests.Request(t, "/api/register", params, nil)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	obj := model.RegistrationResponse{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, params["email"], obj.Email)
+
+	registered, err := database.NewUserRepository(tests.Database).Query(context.Background(), obj.Email)
+	require.NoError(t, err)
+	require.Equal(t, model.RoleApplicant, registered.Role)
+	user := model.User{ID: registered.ID, Role: registered.Role, Email: registered.Email}
+
+	// Login is refused until the e-mail address has been verified
+	res = tests.Request(t, "/api/login", login, nil)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Equal(t, "EMAIL_NOT_VERIFIED", errorType(t, res))
+
+	// The link mailed at registration is recent, so logging in doesn't mail another one
+	_, sent, err := database.NewAuditRepository(tests.Database).List(context.Background(), database.AuditFilter{
+		PersonID: &user.ID,
+		Type:     model.AuthEventVerificationSent,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+
+	// Tokens for other purposes can't verify the address
+	loginToken, _, err := service.SignUserToken(context.Background(), user, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	res = tests.GetRequest(t, "/api/verify?token="+url.QueryEscape(loginToken), nil)
+	require.Equal(t, "INVALID_TOKEN", errorType(t, res))
+
+	// The link in the e-mail contains a verification token
+	verifyToken, _, err := service.SignVerifyToken(context.Background(), user, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	res = tests.GetRequest(t, "/api/verify?token="+url.QueryEscape(verifyToken), nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	res = tests.Request(t, "/api/login", login, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	// Registering the same e-mail address and personal number again gets the same response,
+	// but the address is mailed a notice instead of a new verification link
+	res = tests.Request(t, "/api/register", params, nil)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	again := model.RegistrationResponse{}
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &again))
+	require.Equal(t, obj, again)
+
+	_, sent, err = database.NewAuditRepository(tests.Database).List(context.Background(), database.AuditFilter{
+		PersonID: &user.ID,
+		Type:     model.AuthEventVerificationSent,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+}
+
+// Tests that routes that mail links to users are disabled unless registration and mail are enabled.
+func TestRegisterDisabled(t *testing.T) {
+	t.Parallel()
+
+	cfg := *tests.Config
+	cfg.Auth.Registration = false
+	srv, _, err := api.NewServer(tests.Database, &cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	res := tests.CustomRequest(t, srv, "/api/register", map[string]any{}, nil)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	require.Equal(t, "FEATURE_DISABLED", errorType(t, res))
+
+	// Without mail, links can't be sent for e-mail changes or resets either
+	cfg.Mail.Log = false
+	srv, _, err = api.NewServer(tests.Database, &cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	loginToken, _, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	res = tests.CustomRequest(t, srv, "/api/email", map[string]any{}, map[string]string{"Authorization": "Bearer " + loginToken})
+	require.Equal(t, "FEATURE_DISABLED", errorType(t, res))
+	res = tests.CustomRequest(t, srv, "/api/users/"+strconv.Itoa(tests.MockApplicant.ID)+"/reset", map[string]any{}, recruiterHeaders(t))
+	require.Equal(t, "FEATURE_DISABLED", errorType(t, res))
+}
+
+// Tests that users who are already logged in can't register.
+func TestRegisterLoggedIn(t *testing.T) {
+	t.Parallel()
+
+	srv := databaseServer(t)
+	token, _, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
+	require.NoError(t, err)
+
+	res := tests.CustomRequest(t, srv, "/api/register", map[string]any{}, map[string]string{"Authorization": "Bearer " + token})
+	require.Equal(t, "ALREADY_LOGGED_IN", errorType(t, res))
+}
+
+// Tests that logged in users can change their e-mail address by opening the link sent to the new address.
+func TestChangeEmail(t *testing.T) {
+	t.Parallel()
+
+	loginToken, _, err := service.SignUserToken(context.Background(), tests.MockApplicant9, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	headers := map[string]string{"Authorization": "Bearer " + loginToken}
+	email := "mockuser-changed9@example.com"
+
+	// The wrong current password is rejected like a wrong password at login
+	res := tests.Request(t, "/api/email", map[string]any{"current_password": "wrong", "email": email}, headers)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, "WRONG_IDENTITY", errorType(t, res))
+
+	// Addresses of other users can't be taken
+	res = tests.Request(t, "/api/email", map[string]any{"current_password": tests.MockPassword, "email": tests.MockApplicant.Email}, headers)
+	require.Equal(t, http.StatusConflict, res.StatusCode)
+	require.Equal(t, "IDENTITY_TAKEN", errorType(t, res))
+
+	res = tests.Request(t, "/api/email", map[string]any{"current_password": tests.MockPassword, "email": email}, headers)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	obj := model.EmailChangeResponse{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, email, obj.Email)
+
+	// The current address is used until the link has been opened
+	login := map[string]any{"identity": tests.MockApplicant9.Email, "password": tests.MockPassword}
+	res = tests.Request(t, "/api/login", login, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	changeToken, _, err := service.SignEmailChangeToken(context.Background(), tests.MockApplicant9, email, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	res = tests.GetRequest(t, "/api/verify?token="+url.QueryEscape(changeToken), nil)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	user := model.User{}
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &user))
+	require.Equal(t, tests.MockApplicant9.ID, user.ID)
+	require.Equal(t, email, user.Email)
+
+	res = tests.Request(t, "/api/login", login, nil)
+	require.Equal(t, "WRONG_IDENTITY", errorType(t, res))
+	login["identity"] = email
+	res = tests.Request(t, "/api/login", login, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	// The link can only be used once
+	res = tests.GetRequest(t, "/api/verify?token="+url.QueryEscape(changeToken), nil)
+	require.Equal(t, "INVALID_TOKEN", errorType(t, res))
+}
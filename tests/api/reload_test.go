@@ -0,0 +1,225 @@
+package api_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a copy of the test configuration with a different signing key.
+func configWithSecret(secret string) *config.Config {
+	cfg := *tests.Config
+	cfg.Auth.JWTSecret = secret
+	return &cfg
+}
+
+// Returns true if the server accepts the signature of a token signed with secret.
+// Tokens with the wrong signature are rejected with 401. The offline database has no users,
+// so the request fails later if the signature is accepted.
+func acceptsSecret(t *testing.T, srv *echo.Echo, secret string) bool {
+	t.Helper()
+
+	token, _, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(secret))
+	require.NoError(t, err)
+
+	res := tests.CustomGetRequest(t, srv, "/api/audit", map[string]string{"Authorization": "Bearer " + token})
+	defer res.Body.Close()
+	return res.StatusCode != http.StatusUnauthorized
+}
+
+// Tests that swapping resources changes the signing key without a restart.
+func TestSwapSigningKey(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	srv, reloader, err := api.NewServer(db, configWithSecret("old secret"))
+	require.NoError(t, err)
+	defer srv.Close()
+
+	require.True(t, acceptsSecret(t, srv, "old secret"))
+	require.False(t, acceptsSecret(t, srv, "new secret"))
+
+	require.NoError(t, reloader.Swap(db, configWithSecret("new secret")))
+
+	require.False(t, acceptsSecret(t, srv, "old secret"))
+	require.True(t, acceptsSecret(t, srv, "new secret"))
+	// The database pool is still in use and must not be closed
+	require.NoError(t, db.Ping())
+}
+
+// Tests that swapping resources without a signing key fails and keeps the current key.
+func TestSwapNoSecret(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	srv, reloader, err := api.NewServer(db, configWithSecret("old secret"))
+	require.NoError(t, err)
+	defer srv.Close()
+
+	require.ErrorIs(t, reloader.Swap(db, configWithSecret("")), api.ErrNoSecret)
+	require.True(t, acceptsSecret(t, srv, "old secret"))
+}
+
+// Tests that requests are refused with SERVICE_UNAVAILABLE once the reloader has been closed.
+func TestReloaderClosed(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+
+	srv, reloader, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	require.NoError(t, reloader.Close(context.Background()))
+
+	res := tests.CustomGetRequest(t, srv, "/healthz", map[string]string{})
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	require.Equal(t, "SERVICE_UNAVAILABLE", errorType(t, res))
+}
+
+// Tests that Close stops waiting for in-flight requests once the context is done.
+func TestReloaderCloseTimeout(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+
+	srv, reloader, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv.GET("/test/block", func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.NoContent(http.StatusOK)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res := tests.CustomGetRequest(t, srv, "/test/block", map[string]string{})
+		res.Body.Close()
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, reloader.Close(ctx), context.DeadlineExceeded)
+	// The pool is closed even though the request hasn't finished
+	require.Error(t, db.Ping())
+
+	close(release)
+	<-done
+}
+
+// Tests that the old database pool is closed once in-flight requests have finished with it.
+func TestSwapDrainsRequests(t *testing.T) {
+	t.Parallel()
+
+	oldDB, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	newDB, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer newDB.Close()
+
+	srv, reloader, err := api.NewServer(oldDB, tests.Config)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv.GET("/test/block", func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.NoContent(http.StatusOK)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res := tests.CustomGetRequest(t, srv, "/test/block", map[string]string{})
+		res.Body.Close()
+	}()
+	<-started
+
+	require.NoError(t, reloader.Swap(newDB, tests.Config))
+
+	// The old pool is kept open while the request is in flight
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, oldDB.Ping())
+
+	close(release)
+	<-done
+
+	require.Eventually(t, func() bool {
+		return oldDB.Ping() != nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, newDB.Ping())
+}
+
+// Tests that the signing key is reloaded when the secret file changes.
+// This test can't run in parallel since it changes the environment.
+func TestReloadSecretFile(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "jwt_secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("first secret\n"), 0o600))
+
+	t.Setenv("PORT", "8080")
+	t.Setenv("DATABASE_URL", "postgres://unused")
+	t.Setenv("JWT_SECRET_FILE", secretFile)
+	// Setenv restores JWT_SECRET after the test, in case it was set
+	t.Setenv("JWT_SECRET", "")
+	os.Unsetenv("JWT_SECRET")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, "first secret", cfg.Auth.JWTSecret)
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	srv, reloader, err := api.NewServer(db, cfg)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond)
+
+	// Files can be modified within the resolution of the file system clock
+	writeSecret := func(secret string, modTime time.Time) {
+		require.NoError(t, os.WriteFile(secretFile, []byte(secret), 0o600))
+		require.NoError(t, os.Chtimes(secretFile, modTime, modTime))
+	}
+
+	writeSecret("second secret", time.Now().Add(time.Minute))
+	require.Eventually(t, func() bool {
+		return acceptsSecret(t, srv, "second secret")
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, acceptsSecret(t, srv, "first secret"))
+
+	// An invalid secret is not swapped in
+	writeSecret("", time.Now().Add(2*time.Minute))
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, acceptsSecret(t, srv, "second secret"))
+}
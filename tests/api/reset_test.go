@@ -1,11 +1,12 @@
 package api_test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
-	"os"
 	"testing"
+	"time"
 
 	"github.com/IV1201-Group-2/login-service/api"
 	"github.com/IV1201-Group-2/login-service/database"
@@ -25,10 +26,10 @@ func TestPasswordReset(t *testing.T) {
 	// Generate a new random password every time the test is run.
 	newPassword := tests.RandomStr(16)
 	// Create a new reset token for the user
-	resetToken, _, _ := service.SignResetToken(tests.MockApplicant3, []byte(os.Getenv("JWT_SECRET")))
+	resetToken, _, _ := service.SignResetToken(context.Background(), tests.MockApplicant3, []byte(tests.MockSecret))
 
 	// Go down into service layer and make sure we can't authenticate as this user before reset
-	_, err := service.AuthenticateUser(repository, tests.MockApplicant3.Email, newPassword, nil)
+	_, err := service.AuthenticateUser(context.Background(), repository, tests.MockApplicant3.Email, newPassword, nil)
 	require.ErrorIs(t, err, service.ErrMissingPassword)
 
 	// Send the request
@@ -58,7 +59,7 @@ func TestPasswordReset(t *testing.T) {
 	require.Equal(t, "login", claims.Usage)
 
 	// Go down into service layer again and make sure we can authenticate as this user after reset
-	_, err = service.AuthenticateUser(repository, tests.MockApplicant3.Email, newPassword, nil)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant3.Email, newPassword, nil)
 	require.NoError(t, err)
 }
 
@@ -66,7 +67,7 @@ func TestPasswordReset(t *testing.T) {
 func TestResetMissingParameters(t *testing.T) {
 	t.Parallel()
 
-	resetToken, _, _ := service.SignResetToken(tests.MockApplicant3, []byte(os.Getenv("JWT_SECRET")))
+	resetToken, _, _ := service.SignResetToken(context.Background(), tests.MockApplicant3, []byte(tests.MockSecret))
 	res := tests.Request(t, "/api/reset", map[string]any{}, map[string]string{
 		"Authorization": "Bearer " + resetToken,
 	})
@@ -103,7 +104,7 @@ func TestResetMissingToken(t *testing.T) {
 func TestResetLoginToken(t *testing.T) {
 	t.Parallel()
 
-	testToken, _, _ := service.SignUserToken(tests.MockApplicant, []byte(os.Getenv("JWT_SECRET")))
+	testToken, _, _ := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
 
 	res := tests.Request(t, "/api/reset", map[string]any{
 		"password": tests.MockPassword,
@@ -120,3 +121,84 @@ func TestResetLoginToken(t *testing.T) {
 	require.NoError(t, json.Unmarshal(body, &obj))
 	require.Equal(t, "INVALID_TOKEN", obj.ErrorType)
 }
+
+// Tests that logged in users can change their password with the current one.
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+
+	loginToken, _, err := service.SignUserToken(context.Background(), tests.MockApplicant8, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	headers := map[string]string{"Authorization": "Bearer " + loginToken}
+	newPassword := tests.RandomStr(16)
+
+	// A session on another device
+	ctx := context.Background()
+	sessionRepository := database.NewSessionRepository(tests.Database)
+	now := time.Now()
+	require.NoError(t, sessionRepository.Create(ctx, model.Session{
+		ID:        tests.RandomStr(32),
+		PersonID:  tests.MockApplicant8.ID,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(time.Hour),
+	}))
+
+	// The wrong current password is rejected like a wrong password at login
+	res := tests.Request(t, "/api/password", map[string]any{
+		"current_password": "wrong",
+		"password":         newPassword,
+	}, headers)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	apiErr := api.Error{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &apiErr))
+	require.Equal(t, "WRONG_IDENTITY", apiErr.ErrorType)
+
+	res = tests.Request(t, "/api/password", map[string]any{
+		"current_password": tests.MockPassword,
+		"password":         newPassword,
+	}, headers)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+
+	obj := model.LoginTokenResponse{}
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+
+	claims := model.UserClaims{}
+	_, err = jwt.ParseWithClaims(obj.Token, &claims, mockKeyFunc)
+	require.NoError(t, err)
+	require.Equal(t, tests.MockApplicant8.ID, claims.User.ID)
+	require.Equal(t, model.TokenUsageLogin, claims.Usage)
+
+	// Only the session of the new token is left
+	sessions, err := sessionRepository.List(ctx, tests.MockApplicant8.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, claims.SessionID, sessions[0].ID)
+
+	// Only the new password can be used to log in
+	repository := database.NewUserRepository(tests.Database)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant8.Username, newPassword, nil)
+	require.NoError(t, err)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant8.Username, tests.MockPassword, nil)
+	require.ErrorIs(t, err, service.ErrWrongPassword)
+
+	// Reset tokens can't be used to change the password
+	resetToken, _, err := service.SignResetToken(context.Background(), tests.MockApplicant8, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	res = tests.Request(t, "/api/password", map[string]any{
+		"current_password": newPassword,
+		"password":         tests.MockPassword,
+	}, map[string]string{"Authorization": "Bearer " + resetToken})
+	defer res.Body.Close()
+
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &apiErr))
+	require.Equal(t, "INVALID_TOKEN", apiErr.ErrorType)
+}
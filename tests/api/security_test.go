@@ -0,0 +1,303 @@
+package api_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Creates a server that allows cross-origin requests from https://app.example.com.
+func securityServer(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	cfg := *tests.Config
+	cfg.CORS.AllowOrigins = []string{"https://app.example.com"}
+
+	srv, _, err := api.NewServer(db, &cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// Submits a form to the login route and returns the response.
+// The form is incomplete, so requests that pass the CSRF check fail with MISSING_PARAMETERS.
+func submitLoginForm(t *testing.T, srv *echo.Echo, form url.Values, headers map[string]string, cookies ...*http.Cookie) (*http.Response, api.Error) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	res := rec.Result()
+
+	obj := api.Error{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	return res, obj
+}
+
+// Tests that responses contain security headers and that HSTS is only sent over HTTPS.
+func TestSecurityHeaders(t *testing.T) {
+	t.Parallel()
+
+	srv := securityServer(t)
+
+	res := tests.CustomGetRequest(t, srv, "/healthz", map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, "nosniff", res.Header.Get(echo.HeaderXContentTypeOptions))
+	require.Equal(t, "DENY", res.Header.Get(echo.HeaderXFrameOptions))
+	require.Contains(t, res.Header.Get(echo.HeaderContentSecurityPolicy), "default-src 'none'")
+	require.Contains(t, res.Header.Get(echo.HeaderContentSecurityPolicy), "frame-ancestors 'none'")
+	require.Empty(t, res.Header.Get(echo.HeaderStrictTransportSecurity))
+
+	res = tests.CustomGetRequest(t, srv, "/healthz", map[string]string{echo.HeaderXForwardedProto: "https"})
+	defer res.Body.Close()
+
+	require.Contains(t, res.Header.Get(echo.HeaderStrictTransportSecurity), "max-age=63072000")
+}
+
+// Tests that token responses can't be cached, including errors that contain reset tokens.
+func TestTokenResponsesNotCached(t *testing.T) {
+	t.Parallel()
+
+	srv := securityServer(t)
+
+	res := tests.CustomRequest(t, srv, "/api/login", map[string]any{}, map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	require.Equal(t, "no-store", res.Header.Get(echo.HeaderCacheControl))
+
+	res = tests.CustomGetRequest(t, srv, "/healthz", map[string]string{})
+	defer res.Body.Close()
+
+	require.Empty(t, res.Header.Get(echo.HeaderCacheControl))
+}
+
+// Tests that forms are rejected unless they come from an allowed origin or contain a CSRF token.
+func TestCSRFOrigin(t *testing.T) {
+	t.Parallel()
+
+	srv := securityServer(t)
+
+	cases := map[string]struct {
+		origin  string
+		allowed bool
+	}{
+		"no origin":      {"", false},
+		"cross-site":     {"https://evil.example.net", false},
+		"opaque origin":  {"null", false},
+		"same origin":    {"http://example.com", true},
+		"allowed origin": {"https://app.example.com", true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.origin != "" {
+				headers[echo.HeaderOrigin] = tc.origin
+			}
+			res, obj := submitLoginForm(t, srv, url.Values{"identity": {"user"}}, headers)
+			defer res.Body.Close()
+
+			if tc.allowed {
+				require.Equal(t, http.StatusBadRequest, res.StatusCode)
+				require.Equal(t, "MISSING_PARAMETERS", obj.ErrorType)
+			} else {
+				require.Equal(t, http.StatusForbidden, res.StatusCode)
+				require.Equal(t, "CSRF_FAILED", obj.ErrorType)
+			}
+		})
+	}
+}
+
+// Tests that forms with a token matching the CSRF cookie are accepted.
+func TestCSRFDoubleSubmit(t *testing.T) {
+	t.Parallel()
+
+	srv := securityServer(t)
+
+	res := tests.CustomGetRequest(t, srv, "/api/csrf", map[string]string{})
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.CSRFTokenResponse{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.NotEmpty(t, obj.Token)
+
+	var cookie *http.Cookie
+	for _, c := range res.Cookies() {
+		if c.Name == api.CSRFCookieName {
+			cookie = c
+		}
+	}
+	require.NotNil(t, cookie)
+	require.Equal(t, obj.Token, cookie.Value)
+	require.True(t, cookie.HttpOnly)
+	require.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+
+	// Token in a form field
+	res, apiErr := submitLoginForm(t, srv, url.Values{api.CSRFFormField: {obj.Token}}, map[string]string{}, cookie)
+	defer res.Body.Close()
+	require.Equal(t, "MISSING_PARAMETERS", apiErr.ErrorType)
+
+	// Token in a header
+	res, apiErr = submitLoginForm(t, srv, url.Values{}, map[string]string{api.CSRFHeader: obj.Token}, cookie)
+	defer res.Body.Close()
+	require.Equal(t, "MISSING_PARAMETERS", apiErr.ErrorType)
+
+	// Token that doesn't match the cookie
+	res, apiErr = submitLoginForm(t, srv, url.Values{api.CSRFFormField: {"forged"}}, map[string]string{}, cookie)
+	defer res.Body.Close()
+	require.Equal(t, "CSRF_FAILED", apiErr.ErrorType)
+
+	// Token without the cookie
+	res, apiErr = submitLoginForm(t, srv, url.Values{api.CSRFFormField: {obj.Token}}, map[string]string{})
+	defer res.Body.Close()
+	require.Equal(t, "CSRF_FAILED", apiErr.ErrorType)
+}
+
+// Tests that JSON requests don't need a CSRF token, even from other origins.
+func TestCSRFJSON(t *testing.T) {
+	t.Parallel()
+
+	srv := securityServer(t)
+
+	res := tests.CustomRequest(t, srv, "/api/login", map[string]any{}, map[string]string{echo.HeaderOrigin: "https://evil.example.net"})
+	defer res.Body.Close()
+
+	obj := api.Error{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, "MISSING_PARAMETERS", obj.ErrorType)
+}
+
+// Creates a server in cookie mode that allows cross-origin requests from https://app.example.com.
+func tokenCookieServer(t *testing.T) *echo.Echo {
+	t.Helper()
+
+	cfg := *tests.Config
+	cfg.Auth.TokenCookie = true
+	cfg.CORS.AllowOrigins = []string{"https://app.example.com"}
+
+	srv, _, err := api.NewServer(tests.Database, &cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// Returns the cookie with the specified name set by a response, or nil.
+func findCookie(res *http.Response, name string) *http.Cookie {
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// Tests that requests authenticated by the token cookie are checked for CSRF, even when they are JSON.
+func TestTokenCookieCSRF(t *testing.T) {
+	t.Parallel()
+
+	srv := tokenCookieServer(t)
+	// The token doesn't belong to a session, so logging out doesn't revoke anything
+	token, _, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	cookie := api.TokenCookieName + "=" + token
+	bearer := "Bearer " + token
+
+	cases := map[string]struct {
+		headers map[string]string
+		allowed bool
+	}{
+		"cookie without origin":    {map[string]string{"Cookie": cookie}, false},
+		"cookie from cross-site":   {map[string]string{"Cookie": cookie, echo.HeaderOrigin: "https://evil.example.net"}, false},
+		"cookie from same origin":  {map[string]string{"Cookie": cookie, echo.HeaderOrigin: "http://example.com"}, true},
+		"cookie from allowed site": {map[string]string{"Cookie": cookie, echo.HeaderOrigin: "https://app.example.com"}, true},
+		"header from cross-site":   {map[string]string{echo.HeaderAuthorization: bearer, echo.HeaderOrigin: "https://evil.example.net"}, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			res := tests.CustomRequest(t, srv, "/api/logout", map[string]any{}, tc.headers)
+			defer res.Body.Close()
+
+			if !tc.allowed {
+				obj := api.Error{}
+				body, _ := io.ReadAll(res.Body)
+				require.NoError(t, json.Unmarshal(body, &obj))
+				require.Equal(t, http.StatusForbidden, res.StatusCode)
+				require.Equal(t, "CSRF_FAILED", obj.ErrorType)
+				require.Nil(t, findCookie(res, api.TokenCookieName))
+				return
+			}
+
+			require.Equal(t, http.StatusNoContent, res.StatusCode)
+			if _, ok := tc.headers["Cookie"]; ok {
+				// Logging out removes the cookie
+				removed := findCookie(res, api.TokenCookieName)
+				require.NotNil(t, removed)
+				require.Empty(t, removed.Value)
+				require.Negative(t, removed.MaxAge)
+			} else {
+				require.Nil(t, findCookie(res, api.TokenCookieName))
+			}
+		})
+	}
+}
+
+// Tests that a token cookie that is no longer accepted is removed.
+func TestTokenCookieInvalid(t *testing.T) {
+	t.Parallel()
+
+	srv := tokenCookieServer(t)
+
+	res := tests.CustomGetRequest(t, srv, "/api/sessions", map[string]string{"Cookie": api.TokenCookieName + "=expired"})
+	defer res.Body.Close()
+
+	obj := api.Error{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, "INVALID_TOKEN", obj.ErrorType)
+
+	removed := findCookie(res, api.TokenCookieName)
+	require.NotNil(t, removed)
+	require.Negative(t, removed.MaxAge)
+
+	// The cookie is ignored unless cookie mode is enabled
+	res = tests.CustomGetRequest(t, securityServer(t), "/api/sessions", map[string]string{"Cookie": api.TokenCookieName + "=expired"})
+	defer res.Body.Close()
+
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, "TOKEN_NOT_PROVIDED", obj.ErrorType)
+	require.Nil(t, findCookie(res, api.TokenCookieName))
+}
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the sessions in a response.
+func readSessions(t *testing.T, res *http.Response) []model.Session {
+	t.Helper()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.SessionList{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	return obj.Sessions
+}
+
+// Tests that users can list the devices they are logged in on and log out of them.
+func TestSessions(t *testing.T) {
+	t.Parallel()
+
+	// Log in twice, from two different devices
+	login := func(userAgent string) map[string]string {
+		res := tests.Request(t, "/api/login", map[string]any{
+			"identity": tests.MockRecruiter.Username,
+			"password": tests.MockPassword,
+		}, map[string]string{"User-Agent": userAgent})
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		obj := model.LoginTokenResponse{}
+		body, _ := io.ReadAll(res.Body)
+		require.NoError(t, json.Unmarshal(body, &obj))
+		return map[string]string{"Authorization": "Bearer " + obj.Token}
+	}
+	phone := login("Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36")
+	laptop := login("Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0")
+
+	// Other tests log in as the same user, so only the sessions of this test are checked
+	var phoneSession, laptopSession *model.Session
+	sessions := readSessions(t, tests.GetRequest(t, "/api/sessions", laptop))
+	for i := range sessions {
+		switch sessions[i].Device {
+		case "Chrome on Android":
+			phoneSession = &sessions[i]
+		case "Firefox on Windows":
+			laptopSession = &sessions[i]
+		}
+	}
+	require.NotNil(t, phoneSession)
+	require.NotNil(t, laptopSession)
+	require.False(t, phoneSession.Current)
+	require.True(t, laptopSession.Current)
+
+	// The phone is logged out from the laptop
+	sessions = readSessions(t, tests.Request(t, "/api/sessions/"+phoneSession.ID+"/revoke", nil, laptop))
+	for _, session := range sessions {
+		require.NotEqual(t, phoneSession.ID, session.ID)
+	}
+
+	res := tests.GetRequest(t, "/api/sessions", phone)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Equal(t, "INVALID_TOKEN", errorType(t, res))
+
+	res = tests.Request(t, "/api/sessions/"+phoneSession.ID+"/revoke", nil, laptop)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	require.Equal(t, "SESSION_NOT_FOUND", errorType(t, res))
+
+	// Users can log out of their current session
+	readSessions(t, tests.Request(t, "/api/sessions/"+laptopSession.ID+"/revoke", nil, laptop))
+	res = tests.GetRequest(t, "/api/sessions", laptop)
+	require.Equal(t, "INVALID_TOKEN", errorType(t, res))
+}
+
+// Tests that logging in sets the token cookie in cookie mode, and that logging out revokes its session.
+func TestTokenCookie(t *testing.T) {
+	t.Parallel()
+
+	cfg := *tests.Config
+	cfg.Auth.TokenCookie = true
+	srv, _, err := api.NewServer(tests.Database, &cfg)
+	require.NoError(t, err)
+	defer srv.Close()
+
+	res := tests.CustomRequest(t, srv, "/api/login", map[string]any{
+		"identity": tests.MockRecruiter.Username,
+		"password": tests.MockPassword,
+	}, map[string]string{})
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// The token is still returned in the body for clients that use the Authorization header
+	obj := model.LoginTokenResponse{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+
+	cookie := findCookie(res, api.TokenCookieName)
+	require.NotNil(t, cookie)
+	require.Equal(t, obj.Token, cookie.Value)
+	require.True(t, cookie.HttpOnly)
+	require.True(t, cookie.Secure)
+	require.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	require.Equal(t, "/", cookie.Path)
+	require.WithinDuration(t, time.Now().Add(cfg.Auth.TokenExpiry), cookie.Expires, time.Minute)
+
+	// The cookie is accepted instead of the Authorization header
+	headers := map[string]string{"Cookie": api.TokenCookieName + "=" + cookie.Value}
+	sessions := readSessions(t, tests.CustomGetRequest(t, srv, "/api/sessions", headers))
+	require.NotEmpty(t, sessions)
+
+	headers[echo.HeaderOrigin] = "http://example.com"
+	res = tests.CustomRequest(t, srv, "/api/logout", map[string]any{}, headers)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	removed := findCookie(res, api.TokenCookieName)
+	require.NotNil(t, removed)
+	require.Negative(t, removed.MaxAge)
+
+	// The session has been revoked, so the token no longer works in the cookie or the header
+	res = tests.CustomGetRequest(t, srv, "/api/sessions", headers)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.NotNil(t, findCookie(res, api.TokenCookieName))
+
+	res = tests.CustomGetRequest(t, srv, "/api/sessions", map[string]string{"Authorization": "Bearer " + obj.Token})
+	defer res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
@@ -0,0 +1,164 @@
+package api_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the IDs of goroutines that are running code from the service's own packages.
+func serviceGoroutines() map[string]bool {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+
+	ids := map[string]bool{}
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(stack, "login-service/api.") || strings.Contains(stack, "login-service/database.") {
+			ids[strings.Fields(stack)[1]] = true
+		}
+	}
+	return ids
+}
+
+// Tests that an in-flight request completes when the server receives SIGTERM,
+// and that the database pool and background goroutines are stopped afterwards.
+// This test can't run in parallel since it signals the whole process.
+func TestGracefulShutdown(t *testing.T) {
+	before := serviceGoroutines()
+
+	// The pool is closed by the test, so it can't be shared with other tests
+	db, err := database.Open(tests.Config.Database)
+	require.NoError(t, err)
+
+	srv, reloader, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	srv.HideBanner = true
+	srv.HidePort = true
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		reloader.Watch(watchCtx, 10*time.Millisecond)
+	}()
+
+	started := make(chan struct{})
+	srv.GET("/slow", func(c echo.Context) error {
+		close(started)
+		time.Sleep(500 * time.Millisecond)
+		return c.String(http.StatusOK, "done")
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- api.Serve(srv, "127.0.0.1:0", 5*time.Second)
+	}()
+	require.Eventually(t, func() bool { return srv.ListenerAddr() != nil }, 5*time.Second, 10*time.Millisecond)
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		res, err := http.Get("http://" + srv.ListenerAddr().String() + "/slow")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		results <- result{status: res.StatusCode, body: string(body)}
+	}()
+
+	<-started
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	res := <-results
+	require.NoError(t, res.err)
+	require.Equal(t, http.StatusOK, res.status)
+	require.Equal(t, "done", res.body)
+
+	select {
+	case err := <-served:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+
+	// The listener should be closed after shutdown
+	_, err = http.Get("http://" + srv.ListenerAddr().String() + "/healthz")
+	require.Error(t, err)
+
+	// Stop background work in the same order as main
+	stopWatching()
+	select {
+	case <-watching:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not stop")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, reloader.Close(ctx))
+	require.Error(t, db.Ping())
+
+	require.Eventually(t, func() bool {
+		for id := range serviceGoroutines() {
+			if !before[id] {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Tests that the server is shut down and the error is returned when another server fails.
+// This test can't run in parallel since Serve handles signals for the whole process.
+func TestShutdownServerError(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	srv, _, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	srv.HideBanner = true
+	srv.HidePort = true
+
+	// The other server fails since its address is already in use
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	other := &http.Server{Addr: listener.Addr().String()}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- api.Serve(srv, "127.0.0.1:0", 5*time.Second, other)
+	}()
+
+	select {
+	case err := <-served:
+		require.ErrorContains(t, err, "server on "+other.Addr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+
+	// The main server should be shut down as well
+	if addr := srv.ListenerAddr(); addr != nil {
+		_, err = http.Get("http://" + addr.String() + "/healthz")
+		require.Error(t, err)
+	}
+}
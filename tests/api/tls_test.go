@@ -0,0 +1,157 @@
+package api_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the DER-encoded certificate currently served by cert.
+func servedCertificate(t *testing.T, cert *api.Certificate) []byte {
+	t.Helper()
+
+	current, err := cert.GetCertificate(nil)
+	require.NoError(t, err)
+	return current.Certificate[0]
+}
+
+// Tests that the server serves HTTPS with a self-signed certificate and rejects old TLS versions.
+// This test can't run in parallel since it signals the whole process.
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile, der := tests.WriteCertificate(t, t.TempDir(), "localhost")
+	cert, err := api.LoadCertificate(certFile, keyFile)
+	require.NoError(t, err)
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	srv, _, err := api.NewServer(db, tests.Config)
+	require.NoError(t, err)
+	srv.HideBanner = true
+	srv.HidePort = true
+
+	served := make(chan error, 1)
+	go func() {
+		served <- api.ServeTLS(srv, "127.0.0.1:0", 5*time.Second, api.TLSOptions{Config: api.NewTLSConfig(cert)})
+	}()
+	require.Eventually(t, func() bool { return srv.TLSListenerAddr() != nil }, 5*time.Second, 10*time.Millisecond)
+	url := "https://" + srv.TLSListenerAddr().String() + "/healthz"
+
+	parsed, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(parsed)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}}}
+	res, err := client.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NotNil(t, res.TLS)
+	require.GreaterOrEqual(t, res.TLS.Version, uint16(tls.VersionTLS12))
+
+	// TLS 1.1 and older are rejected
+	oldClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS11}}} // #nosec G402
+	_, err = oldClient.Get(url)
+	require.Error(t, err)
+
+	// Plain HTTP requests are rejected by the TLS listener
+	res, err = http.Get("http://" + srv.TLSListenerAddr().String() + "/healthz")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+	select {
+	case err := <-served:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+}
+
+// Tests that the certificate is reloaded when the files change and kept if the new files are invalid.
+func TestCertificateReload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile, firstDER := tests.WriteCertificate(t, dir, "first")
+	cert, err := api.LoadCertificate(certFile, keyFile)
+	require.NoError(t, err)
+	require.Equal(t, firstDER, servedCertificate(t, cert))
+
+	_, _, secondDER := tests.WriteCertificate(t, dir, "second")
+	require.NoError(t, cert.Reload())
+	require.Equal(t, secondDER, servedCertificate(t, cert))
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+	require.Error(t, cert.Reload())
+	require.Equal(t, secondDER, servedCertificate(t, cert))
+}
+
+// Tests that a missing certificate is reported on startup.
+func TestCertificateMissing(t *testing.T) {
+	t.Parallel()
+
+	_, err := api.LoadCertificate("missing.pem", "missing-key.pem")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Tests that plain HTTP requests are redirected to the same URL over HTTPS.
+func TestRedirectHandler(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		host     string
+		port     string
+		location string
+	}{
+		"custom port":  {"example.com:8080", "8443", "https://example.com:8443/api/users?lang=sv"},
+		"default port": {"example.com", "443", "https://example.com/api/users?lang=sv"},
+		"ipv6":         {"[::1]:8080", "443", "https://[::1]/api/users?lang=sv"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/users?lang=sv", nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+
+			api.RedirectHandler(tc.port).ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusPermanentRedirect, rec.Code)
+			require.Equal(t, tc.location, rec.Header().Get("Location"))
+		})
+	}
+}
+
+// Tests that requests that may contain credentials are refused instead of being redirected.
+func TestRedirectHandlerRefusesPost(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"identity":"user","password":"secret"}`))
+	req.Host = "example.com"
+	rec := httptest.NewRecorder()
+
+	api.RedirectHandler("443").ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Empty(t, rec.Header().Get("Location"))
+	obj := api.Error{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &obj))
+	require.Equal(t, api.ErrHTTPSRequired.ErrorType, obj.ErrorType)
+	require.NotEmpty(t, obj.Message)
+}
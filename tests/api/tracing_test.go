@@ -0,0 +1,63 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tests that a login records a span tree that continues the caller's trace.
+// This test can't run in parallel since it changes the global tracer provider.
+func TestLoginSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+
+	res := tests.Request(t, "/api/login", map[string]any{
+		"identity": tests.MockApplicant.Email,
+		"password": tests.MockPassword,
+	}, map[string]string{
+		"traceparent": "00-" + traceID + "-" + parentID + "-01",
+	})
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID().String() == traceID {
+			spans[span.Name] = span
+		}
+	}
+
+	server, ok := spans["POST /api/login"]
+	require.True(t, ok, "Missing server span")
+	require.Equal(t, trace.SpanKindServer, server.SpanKind)
+	require.Equal(t, parentID, server.Parent.SpanID().String())
+	require.True(t, server.Parent.IsRemote())
+
+	// Every span should be a child of the expected parent
+	expectedParents := map[string]string{
+		"service.AuthenticateUser": "POST /api/login",
+		"SELECT person":            "service.AuthenticateUser",
+		"service.ComparePassword":  "service.AuthenticateUser",
+		"service.StartSession":     "POST /api/login",
+		"INSERT auth_session":      "service.StartSession",
+		"service.SignToken":        "service.StartSession",
+	}
+	for name, parent := range expectedParents {
+		span, ok := spans[name]
+		require.True(t, ok, "Missing span %s", name)
+		require.Equal(t, spans[parent].SpanContext.SpanID(), span.Parent.SpanID(), "Wrong parent for span %s", name)
+	}
+}
@@ -0,0 +1,369 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the Authorization header of the mock recruiter.
+func recruiterHeaders(t *testing.T) map[string]string {
+	t.Helper()
+
+	token, _, err := service.SignUserToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+// Tests that recruiters can list and search users with pagination.
+func TestListUsers(t *testing.T) {
+	t.Parallel()
+
+	res := tests.GetRequest(t, "/api/users?search=APPLICANT2", recruiterHeaders(t))
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.UserPage{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, 1, obj.Total)
+	require.Len(t, obj.Users, 1)
+	require.Equal(t, tests.MockApplicant2.ID, obj.Users[0].ID)
+	require.Equal(t, tests.MockApplicant2.Email, obj.Users[0].Email)
+	require.Equal(t, "Applicant 2", obj.Users[0].Surname)
+	require.False(t, obj.Users[0].HasPassword)
+
+	// Filter by role
+	res = tests.GetRequest(t, "/api/users?role=1", recruiterHeaders(t))
+	defer res.Body.Close()
+
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.NotEmpty(t, obj.Users)
+	for _, user := range obj.Users {
+		require.Equal(t, model.RoleRecruiter, user.Role)
+	}
+
+	// Pagination
+	res = tests.GetRequest(t, "/api/users?limit=2&offset=1", recruiterHeaders(t))
+	defer res.Body.Close()
+
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Len(t, obj.Users, 2)
+	require.Greater(t, obj.Total, 2)
+	require.Equal(t, 2, obj.Limit)
+	require.Equal(t, 1, obj.Offset)
+	require.Equal(t, tests.MockApplicant2.ID, obj.Users[0].ID)
+
+	// Wildcards are matched literally
+	res = tests.GetRequest(t, "/api/users?search=%25", recruiterHeaders(t))
+	defer res.Body.Close()
+
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, 0, obj.Total)
+}
+
+// Tests that recruiters can view the status of a user.
+func TestGetUser(t *testing.T) {
+	t.Parallel()
+
+	// Log in so that the recruiter has a last login
+	res := tests.Request(t, "/api/login", map[string]any{
+		"identity": tests.MockRecruiter.Username,
+		"password": tests.MockPassword,
+	}, map[string]string{})
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = tests.GetRequest(t, "/api/users/"+strconv.Itoa(tests.MockRecruiter.ID), recruiterHeaders(t))
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.UserStatus{}
+	body, _ := io.ReadAll(res.Body)
+
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, tests.MockRecruiter.ID, obj.ID)
+	require.Equal(t, tests.MockRecruiter.Username, obj.Username)
+	require.Equal(t, model.RoleRecruiter, obj.Role)
+	require.True(t, obj.HasPassword)
+	require.NotNil(t, obj.LastLogin)
+	require.False(t, obj.Locked)
+
+	// Unknown user
+	res = tests.GetRequest(t, "/api/users/999999", recruiterHeaders(t))
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	apiErr := api.Error{}
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &apiErr))
+	require.Equal(t, "USER_NOT_FOUND", apiErr.ErrorType)
+}
+
+// Tests that recruiters can log a user out and mail them a reset link, and that the reset is recorded in the audit trail of the user.
+func TestResetUser(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sessionRepository := database.NewSessionRepository(tests.Database)
+	now := time.Now()
+	require.NoError(t, sessionRepository.Create(ctx, model.Session{
+		ID:        tests.RandomStr(32),
+		PersonID:  tests.MockApplicant2.ID,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(time.Hour),
+	}))
+
+	headers := recruiterHeaders(t)
+	headers["User-Agent"] = "reset-user-" + tests.RandomStr(16)
+	res := tests.Request(t, "/api/users/"+strconv.Itoa(tests.MockApplicant2.ID)+"/reset", map[string]any{}, headers)
+	defer res.Body.Close()
+
+	// The token is only mailed to the user
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	body, _ := io.ReadAll(res.Body)
+	require.Empty(t, body)
+
+	sessions, err := sessionRepository.List(ctx, tests.MockApplicant2.ID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	// The user can also be given reset tokens at login, so the event is found by its user agent
+	res = tests.GetRequest(t, "/api/audit?type=reset_token_issued&person_id="+strconv.Itoa(tests.MockApplicant2.ID), recruiterHeaders(t))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	page := model.AuthEventPage{}
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &page))
+	var event *model.AuthEvent
+	for i := range page.Events {
+		if page.Events[i].UserAgent == headers["User-Agent"] {
+			event = &page.Events[i]
+		}
+	}
+	require.NotNil(t, event)
+	require.Equal(t, tests.MockApplicant2.Email, event.Identity)
+	require.Equal(t, "success", event.Outcome)
+
+	// Users without an e-mail address can't be sent a link
+	res = tests.Request(t, "/api/users/"+strconv.Itoa(tests.MockRecruiter.ID)+"/reset", map[string]any{}, recruiterHeaders(t))
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	require.Equal(t, "MISSING_EMAIL", errorType(t, res))
+}
+
+// Returns the types of the events in the audit trail of a user.
+func userEventTypes(t *testing.T, personID int) []string {
+	t.Helper()
+
+	events, _, err := database.NewAuditRepository(tests.Database).List(context.Background(), database.AuditFilter{
+		PersonID: &personID,
+		Limit:    100,
+	})
+	require.NoError(t, err)
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// Tests that recruiters can change the role of other users.
+func TestUpdateUserRole(t *testing.T) {
+	t.Parallel()
+
+	path := "/api/users/" + strconv.Itoa(tests.MockApplicant6.ID) + "/role"
+	// The token contains the role the user had when it was issued
+	token, _, err := service.SignUserToken(context.Background(), tests.MockApplicant6, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	headers := map[string]string{"Authorization": "Bearer " + token}
+
+	res := tests.Request(t, path, map[string]any{"role": model.RoleRecruiter}, recruiterHeaders(t))
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	obj := model.UserStatus{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, model.RoleRecruiter, obj.Role)
+
+	// The new role takes effect without a new token
+	res = tests.GetRequest(t, "/api/users", headers)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	res = tests.Request(t, path, map[string]any{"role": model.RoleApplicant}, recruiterHeaders(t))
+	defer res.Body.Close()
+
+	body, _ = io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	require.Equal(t, model.RoleApplicant, obj.Role)
+
+	res = tests.GetRequest(t, "/api/users", headers)
+	require.Equal(t, "FORBIDDEN", errorType(t, res))
+
+	require.Contains(t, userEventTypes(t, tests.MockApplicant6.ID), model.AuthEventRoleChange)
+}
+
+// Tests that user management rejects invalid requests.
+func TestUpdateUserRoleInvalid(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		path    string
+		params  map[string]any
+		status  int
+		errType string
+	}{
+		"own role":     {"/api/users/" + strconv.Itoa(tests.MockRecruiter.ID) + "/role", map[string]any{"role": 2}, http.StatusForbidden, "FORBIDDEN"},
+		"unknown user": {"/api/users/999999/role", map[string]any{"role": 2}, http.StatusNotFound, "USER_NOT_FOUND"},
+		"unknown role": {"/api/users/" + strconv.Itoa(tests.MockApplicant6.ID) + "/role", map[string]any{"role": 3}, http.StatusBadRequest, "MISSING_PARAMETERS"},
+		"no role":      {"/api/users/" + strconv.Itoa(tests.MockApplicant6.ID) + "/role", map[string]any{}, http.StatusBadRequest, "MISSING_PARAMETERS"},
+		"invalid id":   {"/api/users/abc/role", map[string]any{"role": 2}, http.StatusBadRequest, "MALFORMED_REQUEST"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			res := tests.Request(t, tc.path, tc.params, recruiterHeaders(t))
+			defer res.Body.Close()
+
+			require.Equal(t, tc.status, res.StatusCode)
+
+			obj := api.Error{}
+			body, _ := io.ReadAll(res.Body)
+			require.NoError(t, json.Unmarshal(body, &obj))
+			require.Equal(t, tc.errType, obj.ErrorType)
+		})
+	}
+}
+
+// Tests that only recruiters can manage users.
+func TestUsersForbidden(t *testing.T) {
+	t.Parallel()
+
+	srv := databaseServer(t)
+	applicantToken, _, _ := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
+	resetToken, _, _ := service.SignResetToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+
+	headers := map[string]map[string]string{
+		"TOKEN_NOT_PROVIDED": {},
+		"FORBIDDEN":          {"Authorization": "Bearer " + applicantToken},
+		"INVALID_TOKEN":      {"Authorization": "Bearer " + resetToken},
+	}
+
+	for errType, header := range headers {
+		for _, path := range []string{"/api/users", "/api/users/0"} {
+			res := tests.CustomGetRequest(t, srv, path, header)
+			defer res.Body.Close()
+
+			obj := api.Error{}
+			body, _ := io.ReadAll(res.Body)
+			require.NoError(t, json.Unmarshal(body, &obj))
+			require.Equal(t, errType, obj.ErrorType, path)
+		}
+		for _, path := range []string{"/api/users/0/reset", "/api/users/0/role", "/api/users/0/disable", "/api/users/0/enable"} {
+			res := tests.CustomRequest(t, srv, path, map[string]any{"role": 1}, header)
+			defer res.Body.Close()
+
+			obj := api.Error{}
+			body, _ := io.ReadAll(res.Body)
+			require.NoError(t, json.Unmarshal(body, &obj))
+			require.Equal(t, errType, obj.ErrorType, path)
+		}
+	}
+}
+
+// Returns the error type of a response.
+func errorType(t *testing.T, res *http.Response) string {
+	t.Helper()
+	defer res.Body.Close()
+
+	obj := api.Error{}
+	body, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(body, &obj))
+	return obj.ErrorType
+}
+
+// Tests that recruiters can disable accounts, which stops the user from logging in or resetting their password.
+func TestDisableUser(t *testing.T) {
+	t.Parallel()
+
+	// Tokens issued before the account is disabled
+	loginToken, _, err := service.SignUserToken(context.Background(), tests.MockRecruiter2, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	loginHeaders := map[string]string{"Authorization": "Bearer " + loginToken}
+	resetToken, _, err := service.SignResetToken(context.Background(), tests.MockApplicant7, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	resetHeaders := map[string]string{"Authorization": "Bearer " + resetToken}
+
+	for _, id := range []int{tests.MockRecruiter2.ID, tests.MockApplicant7.ID} {
+		res := tests.Request(t, "/api/users/"+strconv.Itoa(id)+"/disable", map[string]any{}, recruiterHeaders(t))
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		obj := model.UserStatus{}
+		body, _ := io.ReadAll(res.Body)
+		require.NoError(t, json.Unmarshal(body, &obj))
+		require.True(t, obj.Disabled)
+	}
+
+	// Login with the correct password
+	res := tests.Request(t, "/api/login", map[string]any{"identity": tests.MockRecruiter2.Username, "password": tests.MockPassword}, nil)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Equal(t, "ACCOUNT_DISABLED", errorType(t, res))
+	// Login with the wrong password
+	res = tests.Request(t, "/api/login", map[string]any{"identity": tests.MockRecruiter2.Username, "password": "wrong"}, nil)
+	require.Equal(t, "WRONG_IDENTITY", errorType(t, res))
+	// Login without a password doesn't hand out a reset token
+	res = tests.Request(t, "/api/login", map[string]any{"identity": tests.MockApplicant7.Email, "password": "password"}, nil)
+	require.Equal(t, "ACCOUNT_DISABLED", errorType(t, res))
+	res = tests.Request(t, "/api/users/"+strconv.Itoa(tests.MockApplicant7.ID)+"/reset", map[string]any{}, recruiterHeaders(t))
+	require.Equal(t, "ACCOUNT_DISABLED", errorType(t, res))
+
+	// Existing tokens stop working
+	res = tests.GetRequest(t, "/api/users", loginHeaders)
+	require.Equal(t, "ACCOUNT_DISABLED", errorType(t, res))
+	res = tests.Request(t, "/api/reset", map[string]any{"password": "newpassword"}, resetHeaders)
+	require.Equal(t, "ACCOUNT_DISABLED", errorType(t, res))
+
+	// Enabling the account makes the login token work again
+	res = tests.Request(t, "/api/users/"+strconv.Itoa(tests.MockRecruiter2.ID)+"/enable", map[string]any{}, recruiterHeaders(t))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+	res = tests.GetRequest(t, "/api/users", loginHeaders)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	require.Subset(t, userEventTypes(t, tests.MockRecruiter2.ID), []string{model.AuthEventAccountDisabled, model.AuthEventAccountEnabled})
+	require.Contains(t, userEventTypes(t, tests.MockApplicant7.ID), model.AuthEventAccountDisabled)
+}
+
+// Tests that recruiters can't disable their own account.
+func TestDisableSelf(t *testing.T) {
+	t.Parallel()
+
+	res := tests.Request(t, "/api/users/"+strconv.Itoa(tests.MockRecruiter.ID)+"/disable", map[string]any{}, recruiterHeaders(t))
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Equal(t, "FORBIDDEN", errorType(t, res))
+}
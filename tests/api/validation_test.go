@@ -0,0 +1,178 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// Error with the details of a MISSING_PARAMETERS error.
+type validationError struct {
+	ErrorType string             `json:"error"`
+	Details   []model.FieldError `json:"details"`
+}
+
+// Sends a request with a raw body to an existing server and returns the decoded error.
+// Forms are sent from the same origin to pass the CSRF check.
+func rawRequest(t *testing.T, srv *echo.Echo, method string, path string, contentType string, body string, token string) validationError {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	req.Header.Set(echo.HeaderOrigin, "http://example.com")
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	res := rec.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	obj := validationError{}
+	data, _ := io.ReadAll(res.Body)
+	require.NoError(t, json.Unmarshal(data, &obj))
+	return obj
+}
+
+// Tests that invalid parameters are listed in the details of MISSING_PARAMETERS
+// and that requests that can't be parsed return MALFORMED_REQUEST.
+func TestValidationDetails(t *testing.T) {
+	t.Parallel()
+
+	srv := databaseServer(t)
+	resetToken, _, err := service.SignResetToken(context.Background(), tests.MockApplicant3, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	recruiterToken, _, err := service.SignUserToken(context.Background(), tests.MockRecruiter, []byte(tests.MockSecret))
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		token       string
+
+		errorType string
+		details   []model.FieldError
+	}{
+		"json missing fields": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationJSON, `{}`, "",
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "identity", Rule: "required"}, {Field: "password", Rule: "required"}},
+		},
+		"json missing field": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationJSON, `{"identity": "user"}`, "",
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "password", Rule: "required"}},
+		},
+		"json empty body": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationJSON, ``, "",
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "identity", Rule: "required"}, {Field: "password", Rule: "required"}},
+		},
+		"json wrong type": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationJSON, `{"identity": 5, "password": "password"}`, "",
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "identity", Rule: model.FieldRuleType, Param: "string"}},
+		},
+		"json wrong role type": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationJSON, `{"identity": "user", "password": "password", "role": "recruiter"}`, "",
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "role", Rule: model.FieldRuleType, Param: "integer"}},
+		},
+		"json syntax error": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationJSON, `{"identity": "user",`, "",
+			"MALFORMED_REQUEST", nil,
+		},
+		"json not an object": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationJSON, `"identity"`, "",
+			"MALFORMED_REQUEST", nil,
+		},
+		"unsupported content type": {
+			http.MethodPost, "/api/login", "text/csv", `identity,password`, "",
+			"MALFORMED_REQUEST", nil,
+		},
+		"form missing field": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationForm, `identity=user`, "",
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "password", Rule: "required"}},
+		},
+		"form wrong type": {
+			http.MethodPost, "/api/login", echo.MIMEApplicationForm, `identity=user&password=password&role=recruiter`, "",
+			"MALFORMED_REQUEST", nil,
+		},
+		"reset missing password": {
+			http.MethodPost, "/api/reset", echo.MIMEApplicationJSON, `{}`, resetToken,
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "password", Rule: "required"}},
+		},
+		"reset wrong type": {
+			http.MethodPost, "/api/reset", echo.MIMEApplicationJSON, `{"password": ["password"]}`, resetToken,
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "password", Rule: model.FieldRuleType, Param: "string"}},
+		},
+		"reset syntax error": {
+			http.MethodPost, "/api/reset", echo.MIMEApplicationJSON, `{password}`, resetToken,
+			"MALFORMED_REQUEST", nil,
+		},
+		"password missing fields": {
+			http.MethodPost, "/api/password", echo.MIMEApplicationJSON, `{}`, recruiterToken,
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "current_password", Rule: "required"}, {Field: "password", Rule: "required"}},
+		},
+		"email invalid address": {
+			http.MethodPost, "/api/email", echo.MIMEApplicationJSON, `{"email": "mock"}`, recruiterToken,
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "current_password", Rule: "required"}, {Field: "email", Rule: "email"}},
+		},
+		"register invalid fields": {
+			http.MethodPost, "/api/register", echo.MIMEApplicationJSON,
+			`{"name": "Mock", "surname": "Applicant", "personal_number": "199001011238", "email": "mock", "password": "short"}`, "",
+			"MISSING_PARAMETERS", []model.FieldError{
+				{Field: "personal_number", Rule: "personal_number"},
+				{Field: "email", Rule: "email"},
+				{Field: "password", Rule: "min", Param: "8"},
+			},
+		},
+		"query out of range": {
+			http.MethodGet, "/api/audit?limit=500&person_id=-1", "", ``, recruiterToken,
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "person_id", Rule: "min", Param: "0"}, {Field: "limit", Rule: "max", Param: "200"}},
+		},
+		"query not in set": {
+			http.MethodGet, "/api/audit?type=logout", "", ``, recruiterToken,
+			"MISSING_PARAMETERS", []model.FieldError{{Field: "type", Rule: "oneof", Param: "login reset password_change email_change login_token_issued reset_token_issued verification_sent role_change account_disabled account_enabled"}},
+		},
+		"query wrong type": {
+			http.MethodGet, "/api/audit?limit=many", "", ``, recruiterToken,
+			"MALFORMED_REQUEST", nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			obj := rawRequest(t, srv, tc.method, tc.path, tc.contentType, tc.body, tc.token)
+			require.Equal(t, tc.errorType, obj.ErrorType)
+			require.Equal(t, tc.details, obj.Details)
+		})
+	}
+}
+
+// Tests that validation details are included in problem details.
+func TestValidationDetailsProblem(t *testing.T) {
+	t.Parallel()
+
+	srv := offlineServer(t)
+
+	res := tests.CustomRequest(t, srv, "/api/v2/login", map[string]any{"identity": "user"}, map[string]string{})
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	obj := readProblem(t, res)
+	require.Equal(t, api.ErrMissingParameters.ErrorType, obj.ErrorType)
+	require.Equal(t, []any{map[string]any{"field": "password", "rule": "required"}}, obj.Details)
+}
@@ -0,0 +1,235 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/config"
+	"github.com/stretchr/testify/require"
+)
+
+// Sets the values that must always be provided.
+// Tests using this function can't run in parallel since they change the environment.
+func setRequired(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("PORT", "8080")
+	t.Setenv("DATABASE_URL", "postgres://localhost/login")
+	t.Setenv("JWT_SECRET", "secret")
+}
+
+// Writes a configuration file and points CONFIG_FILE to it.
+func writeConfigFile(t *testing.T, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv(config.FileEnv, path)
+}
+
+// Test that defaults are used for values that aren't set.
+func TestDefaults(t *testing.T) {
+	setRequired(t)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	defaults := config.Default()
+	defaults.Port = "8080"
+	defaults.Database.URL = "postgres://localhost/login"
+	defaults.Auth.JWTSecret = "secret"
+	require.Equal(t, defaults, cfg)
+}
+
+// Test that values are read from the configuration file and overridden by the environment.
+func TestFileAndEnvironment(t *testing.T) {
+	setRequired(t)
+	writeConfigFile(t, `
+port: 9000
+database:
+  max_connections: 20
+  max_idle_connections: 4
+auth:
+  token_expiry: 30m
+  password_cost: 12
+log:
+  format: json
+`)
+	t.Setenv("DATABASE_MAX_CONNECTIONS", "10")
+	t.Setenv("CORS_ALLOW_ORIGINS", "https://app.example.com, https://*.example.org")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	// The environment takes precedence over the file
+	require.Equal(t, "8080", cfg.Port)
+	require.Equal(t, 10, cfg.Database.MaxConnections)
+
+	require.Equal(t, 4, cfg.Database.MaxIdleConnections)
+	require.Equal(t, 30*time.Minute, cfg.Auth.TokenExpiry)
+	require.Equal(t, 12, cfg.Auth.PasswordCost)
+	require.Equal(t, "json", cfg.Log.Format)
+	require.Equal(t, []string{"https://app.example.com", "https://*.example.org"}, cfg.CORS.AllowOrigins)
+	// Values missing from the file keep their defaults
+	require.Equal(t, 10*time.Minute, cfg.Auth.ResetTokenExpiry)
+}
+
+// Test that values that can't be parsed are reported instead of being ignored.
+func TestInvalidEnvironment(t *testing.T) {
+	setRequired(t)
+	t.Setenv("DATABASE_MAX_CONNECTIONS", "many")
+	t.Setenv("TOKEN_EXPIRY", "1 hour")
+	t.Setenv("DATABASE_CONNECT_RETRIES", "forever")
+
+	_, err := config.Load()
+	require.ErrorIs(t, err, config.ErrInvalid)
+	require.ErrorContains(t, err, `$DATABASE_MAX_CONNECTIONS "many" is not an integer`)
+	require.ErrorContains(t, err, `$TOKEN_EXPIRY "1 hour" is not a duration`)
+	require.ErrorContains(t, err, `$DATABASE_CONNECT_RETRIES "forever" is not an integer`)
+}
+
+// Test that a configuration file that doesn't exist or can't be parsed is an error.
+func TestInvalidFile(t *testing.T) {
+	setRequired(t)
+
+	t.Setenv(config.FileEnv, filepath.Join(t.TempDir(), "missing.yaml"))
+	_, err := config.Load()
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	writeConfigFile(t, "database: [")
+	_, err = config.Load()
+	require.ErrorIs(t, err, config.ErrInvalid)
+}
+
+// Test that every invalid value is reported at once.
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Port = "http"
+	cfg.MetricsPort = "metrics"
+	cfg.Database.MaxConnections = 2
+	cfg.Database.MaxIdleConnections = 4
+	cfg.Database.ConnectRetries = -1
+	cfg.Auth.PasswordCost = 64
+	cfg.Auth.TokenCookieSameSite = "sometimes"
+	cfg.Auth.MaxFailedAttempts = -1
+	cfg.Auth.LockoutPeriod = 0
+	cfg.Log.Level = "loud"
+	cfg.Tracing.Exporter = "jaeger"
+	cfg.TLS.KeyFile = "key.pem"
+	cfg.TLS.RedirectPort = "80"
+	cfg.CORS.AllowOrigins = []string{"*", "example.com"}
+	cfg.CORS.AllowCredentials = true
+	cfg.Mail.From = "nobody"
+	cfg.Mail.SMTPAddr = "smtp.example.com"
+	cfg.Mail.Log = true
+	cfg.Mail.VerifyURL = "/api/verify"
+	cfg.Mail.ResetURL = "reset"
+
+	err := cfg.Validate()
+	require.ErrorIs(t, err, config.ErrInvalid)
+	for _, name := range []string{"$PORT", "$METRICS_PORT", "$DATABASE_URL", "$DATABASE_MAX_IDLE_CONNECTIONS", "$DATABASE_CONNECT_RETRIES", "$JWT_SECRET",
+		"$PASSWORD_COST", "$TOKEN_COOKIE_SAME_SITE", "$MAX_FAILED_ATTEMPTS", "$LOCKOUT_PERIOD", "$LOG_LEVEL", "$TRACING_EXPORTER", "$TLS_KEY_FILE", "$TLS_REDIRECT_PORT",
+		`$CORS_ALLOW_ORIGINS) "example.com"`, "$CORS_ALLOW_CREDENTIALS", "$MAIL_FROM", "$MAIL_SMTP_ADDR", "$MAIL_LOG", "$MAIL_VERIFY_URL", "$MAIL_RESET_URL"} {
+		require.ErrorContains(t, err, name)
+	}
+	require.NotContains(t, err.Error(), "$LOG_FORMAT")
+}
+
+// Test that links mailed through SMTP must point to configured verification and reset pages.
+func TestValidateVerifyURL(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Port = "8080"
+	cfg.Database.URL = "postgres://localhost/login"
+	cfg.Auth.JWTSecret = "secret"
+	cfg.Mail.Log = true
+	require.NoError(t, cfg.Validate())
+
+	cfg.Mail.Log = false
+	cfg.Mail.SMTPAddr = "smtp.example.com:587"
+	require.ErrorContains(t, cfg.Validate(), "mail.verify_url ($MAIL_VERIFY_URL) must be set")
+	require.ErrorContains(t, cfg.Validate(), "mail.reset_url ($MAIL_RESET_URL) must be set")
+
+	cfg.Mail.VerifyURL = "https://example.com/verify"
+	cfg.Mail.ResetURL = "https://example.com/reset"
+	require.NoError(t, cfg.Validate())
+}
+
+// Test that mail is only required when applicants can register themselves.
+func TestValidateRegistration(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Port = "8080"
+	cfg.Database.URL = "postgres://localhost/login"
+	cfg.Auth.JWTSecret = "secret"
+	require.NoError(t, cfg.Validate())
+
+	cfg.Auth.Registration = true
+	require.ErrorContains(t, cfg.Validate(), "auth.registration ($REGISTRATION) requires mail.smtp_addr ($MAIL_SMTP_ADDR)")
+
+	cfg.Mail.Log = true
+	require.NoError(t, cfg.Validate())
+}
+
+// Test that metrics can't be served on a port that is already used by the API.
+func TestValidateMetricsPort(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Port = "8080"
+	cfg.Database.URL = "postgres://localhost/login"
+	cfg.Auth.JWTSecret = "secret"
+	cfg.MetricsPort = "9090"
+	require.NoError(t, cfg.Validate())
+
+	cfg.MetricsPort = cfg.Port
+	require.ErrorContains(t, cfg.Validate(), "metrics_port ($METRICS_PORT) must be different")
+}
+
+// Test that secrets can be read from files.
+func TestSecretFiles(t *testing.T) {
+	setRequired(t)
+
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "jwt_secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("file secret\n"), 0o600))
+	urlFile := filepath.Join(dir, "database_url")
+	require.NoError(t, os.WriteFile(urlFile, []byte("postgres://file/login"), 0o600))
+
+	os.Unsetenv("JWT_SECRET")
+	os.Unsetenv("DATABASE_URL")
+	t.Setenv("JWT_SECRET_FILE", secretFile)
+	t.Setenv("DATABASE_URL_FILE", urlFile)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, "file secret", cfg.Auth.JWTSecret)
+	require.Equal(t, "postgres://file/login", cfg.Database.URL)
+	require.ElementsMatch(t, []string{secretFile, urlFile}, cfg.SecretFiles)
+}
+
+// Test that a secret can't be set both directly and from a file.
+func TestSecretFileConflict(t *testing.T) {
+	setRequired(t)
+	t.Setenv("JWT_SECRET_FILE", filepath.Join(t.TempDir(), "jwt_secret"))
+
+	_, err := config.Load()
+	require.ErrorIs(t, err, config.ErrInvalid)
+	require.ErrorContains(t, err, "only one of $JWT_SECRET and $JWT_SECRET_FILE can be set")
+}
+
+// Test that a missing secret file is an error.
+func TestSecretFileMissing(t *testing.T) {
+	setRequired(t)
+	os.Unsetenv("JWT_SECRET")
+	t.Setenv("JWT_SECRET_FILE", filepath.Join(t.TempDir(), "jwt_secret"))
+
+	_, err := config.Load()
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
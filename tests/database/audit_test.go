@@ -0,0 +1,119 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that events can be recorded and listed from the audit trail.
+func TestAuditInsertList(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewAuditRepository(tests.Database)
+	identity := "audit-" + tests.RandomStr(16) + "@example.com"
+	start := time.Now().Add(-time.Minute)
+
+	for i, outcome := range []string{"WRONG_IDENTITY", "WRONG_IDENTITY", "success"} {
+		err := repository.Insert(context.Background(), model.AuthEvent{
+			Type:      model.AuthEventLogin,
+			PersonID:  &tests.MockApplicant.ID,
+			Identity:  identity,
+			IP:        "192.0.2.1",
+			UserAgent: "test",
+			Outcome:   outcome,
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+		})
+		require.NoError(t, err)
+	}
+
+	// List all events for the identity, newest first
+	events, total, err := repository.List(context.Background(), database.AuditFilter{
+		Identity: identity,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, total)
+	require.Len(t, events, 3)
+	require.Equal(t, "success", events[0].Outcome)
+	require.Equal(t, tests.MockApplicant.ID, *events[0].PersonID)
+	require.Equal(t, "192.0.2.1", events[0].IP)
+	require.Equal(t, "test", events[0].UserAgent)
+
+	// Filter by outcome and paginate
+	events, total, err = repository.List(context.Background(), database.AuditFilter{
+		Identity: identity,
+		Outcome:  "WRONG_IDENTITY",
+		Limit:    1,
+		Offset:   1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Len(t, events, 1)
+	require.Equal(t, "WRONG_IDENTITY", events[0].Outcome)
+
+	// Filter by time range
+	to := start.Add(time.Second)
+	events, total, err = repository.List(context.Background(), database.AuditFilter{
+		Identity: identity,
+		To:       &to,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Len(t, events, 1)
+}
+
+// Test that the nth newest event with an outcome is found for each user that has enough of them from one address.
+func TestAuditNthNewest(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewAuditRepository(tests.Database)
+	outcome := "TEST_" + tests.RandomStr(16)
+	start := time.Now().Add(-time.Minute)
+
+	events := []struct {
+		personID int
+		ip       string
+	}{
+		{tests.MockApplicant.ID, "192.0.2.1"},
+		{tests.MockApplicant.ID, "192.0.2.2"},
+		{tests.MockApplicant.ID, "192.0.2.1"},
+		{tests.MockRecruiter.ID, "192.0.2.1"},
+	}
+	for i, event := range events {
+		err := repository.Insert(context.Background(), model.AuthEvent{
+			Type:      model.AuthEventLogin,
+			PersonID:  &event.personID,
+			IP:        event.ip,
+			Outcome:   outcome,
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+		})
+		require.NoError(t, err)
+	}
+
+	ids := []int{tests.MockApplicant.ID, tests.MockRecruiter.ID}
+	found, err := repository.NthNewest(context.Background(), ids, "", outcome, start, 2)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.WithinDuration(t, start, found[tests.MockApplicant.ID], time.Millisecond)
+
+	found, err = repository.NthNewest(context.Background(), ids, "192.0.2.1", outcome, start, 2)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	// Events from other addresses are not counted
+	found, err = repository.NthNewest(context.Background(), ids, "192.0.2.2", outcome, start, 2)
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	// Older events are not counted
+	found, err = repository.NthNewest(context.Background(), ids, "", outcome, start.Add(time.Second/2), 2)
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
@@ -0,0 +1,145 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the circuit breaker opens after repeated failures and reports when to retry.
+func TestBreakerOpens(t *testing.T) {
+	t.Parallel()
+
+	breaker := database.NewBreaker(tests.Unreachable)
+	t.Cleanup(breaker.Stop)
+
+	for i := 0; i < database.BreakerThreshold-1; i++ {
+		require.NoError(t, breaker.Allow())
+		breaker.Record(driver.ErrBadConn)
+	}
+	require.False(t, breaker.IsOpen())
+
+	breaker.Record(driver.ErrBadConn)
+	require.True(t, breaker.IsOpen())
+
+	err := breaker.Allow()
+	require.ErrorIs(t, err, database.ErrCircuitOpen)
+
+	after, ok := database.RetryAfter(err)
+	require.True(t, ok)
+	require.Positive(t, after)
+}
+
+// Test that a successful call resets the failure count.
+func TestBreakerResets(t *testing.T) {
+	t.Parallel()
+
+	breaker := database.NewBreaker(tests.Unreachable)
+	t.Cleanup(breaker.Stop)
+
+	for i := 0; i < database.BreakerThreshold-1; i++ {
+		breaker.Record(driver.ErrBadConn)
+	}
+	breaker.Record(nil)
+	breaker.Record(driver.ErrBadConn)
+
+	require.False(t, breaker.IsOpen())
+	require.NoError(t, breaker.Allow())
+}
+
+// Test that the circuit closes once the background probe reaches the database.
+func TestBreakerRecovers(t *testing.T) {
+	t.Parallel()
+
+	breaker := database.NewBreaker(func(ctx context.Context) error { return nil })
+	t.Cleanup(breaker.Stop)
+
+	for i := 0; i < database.BreakerThreshold; i++ {
+		breaker.Record(driver.ErrBadConn)
+	}
+	require.True(t, breaker.IsOpen())
+	require.ErrorIs(t, breaker.Allow(), database.ErrCircuitOpen)
+
+	require.Eventually(t, func() bool { return !breaker.IsOpen() }, 5*time.Second, 50*time.Millisecond)
+	require.NoError(t, breaker.Allow())
+}
+
+// Test that the wait before the next probe grows when the database is still unavailable.
+func TestBreakerStillDown(t *testing.T) {
+	t.Parallel()
+
+	probed := make(chan struct{}, 1)
+	breaker := database.NewBreaker(func(ctx context.Context) error {
+		select {
+		case probed <- struct{}{}:
+		default:
+		}
+		return tests.Unreachable(ctx)
+	})
+	t.Cleanup(breaker.Stop)
+
+	for i := 0; i < database.BreakerThreshold; i++ {
+		breaker.Record(driver.ErrBadConn)
+	}
+	<-probed
+
+	require.Eventually(t, func() bool {
+		after, ok := database.RetryAfter(breaker.Allow())
+		return ok && after > time.Second
+	}, 5*time.Second, 50*time.Millisecond)
+	require.True(t, breaker.IsOpen())
+}
+
+// Test that only errors that show that the database can't be reached open the circuit.
+func TestBreakerIgnoresRejectedStatements(t *testing.T) {
+	t.Parallel()
+
+	breaker := database.NewBreaker(tests.Unreachable)
+	t.Cleanup(breaker.Stop)
+
+	for i := 0; i < database.BreakerThreshold; i++ {
+		breaker.Record(&pq.Error{Code: "23505"})
+		breaker.Record(sql.ErrNoRows)
+		breaker.Record(errors.New("sql: Scan error on column index 0"))
+		breaker.Record(context.Canceled)
+		breaker.Record(context.DeadlineExceeded)
+	}
+	require.False(t, breaker.IsOpen())
+
+	// The database can't be reached when it is shutting down
+	for i := 0; i < database.BreakerThreshold; i++ {
+		breaker.Record(&pq.Error{Code: "57P01"})
+	}
+	require.True(t, breaker.IsOpen())
+}
+
+// Test that queries fail fast once the database has been unreachable for a while.
+func TestRepositoryCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open(tests.UnreachableDriver, "")
+	require.NoError(t, err)
+
+	repository := database.NewUserRepository(db)
+	defer database.Close(db)
+
+	for i := 0; i < database.BreakerThreshold; i++ {
+		_, err = repository.Query(context.Background(), "test")
+		require.ErrorIs(t, err, database.ErrQueryFailed)
+	}
+
+	_, err = repository.Query(context.Background(), "test")
+	require.ErrorIs(t, err, database.ErrCircuitOpen)
+
+	// Repositories that use the same pool share the breaker
+	err = database.NewSessionRepository(db).RevokeAll(context.Background(), 1)
+	require.ErrorIs(t, err, database.ErrCircuitOpen)
+}
@@ -1,10 +1,12 @@
 package database_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"testing"
 
+	"github.com/IV1201-Group-2/login-service/config"
 	"github.com/IV1201-Group-2/login-service/database"
 	"github.com/IV1201-Group-2/login-service/tests"
 	// Imports ChaiSQL driver.
@@ -53,10 +55,22 @@ func TestServiceErrors(t *testing.T) {
 func TestConnectionFailed(t *testing.T) {
 	t.Parallel()
 
-	_, err := database.Open(tests.RandomStr(16))
+	_, err := database.Open(config.Database{URL: tests.RandomStr(16)})
 	require.ErrorIs(t, err, database.ErrConnectionFailed)
 }
 
+// Test that database.Open fails if the migrations of the tables owned by the service have not been applied.
+func TestSchemaOutdated(t *testing.T) {
+	t.Parallel()
+
+	// The tables can't be found in a schema that doesn't exist
+	cfg := tests.Config.Database
+	cfg.URL += "&search_path=outdated"
+	_, err := database.Open(cfg)
+	require.ErrorIs(t, err, database.ErrSchemaOutdated)
+	require.ErrorContains(t, err, "auth_session.revoked_at")
+}
+
 // Test that queries fail with an error if the connection is down.
 func TestConnectionDown(t *testing.T) {
 	t.Parallel()
@@ -68,6 +82,6 @@ func TestConnectionDown(t *testing.T) {
 
 	repository := database.NewUserRepository(db)
 
-	_, err = repository.Query("test")
+	_, err = repository.Query(context.Background(), "test")
 	require.ErrorIs(t, err, database.ErrQueryFailed)
 }
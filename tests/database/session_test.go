@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns the session with the specified ID, if it is in the list.
+func findSession(sessions []model.Session, id string) (model.Session, bool) {
+	for _, session := range sessions {
+		if session.ID == id {
+			return session, true
+		}
+	}
+	return model.Session{}, false
+}
+
+// Tests that sessions can be listed, touched and revoked by the user they belong to.
+func TestSessions(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewSessionRepository(tests.Database)
+	now := time.Now().Truncate(time.Second)
+	session := model.Session{
+		ID:        "database-" + tests.RandomStr(16),
+		PersonID:  tests.MockApplicant.ID,
+		Device:    "Firefox on Windows",
+		IP:        "192.0.2.1",
+		CreatedAt: now.Add(-time.Hour),
+		LastSeen:  now.Add(-time.Hour),
+		ExpiresAt: now.Add(time.Hour),
+	}
+	expired := session
+	expired.ID = "database-expired-" + tests.RandomStr(16)
+	expired.ExpiresAt = now.Add(-time.Minute)
+
+	require.NoError(t, repository.Create(context.Background(), session))
+	require.NoError(t, repository.Create(context.Background(), expired))
+
+	sessions, err := repository.List(context.Background(), tests.MockApplicant.ID)
+	require.NoError(t, err)
+	listed, ok := findSession(sessions, session.ID)
+	require.True(t, ok)
+	require.Equal(t, session.Device, listed.Device)
+	require.Equal(t, session.IP, listed.IP)
+	require.True(t, session.CreatedAt.Equal(listed.CreatedAt))
+	_, ok = findSession(sessions, expired.ID)
+	require.False(t, ok)
+
+	// Touching a session updates when it was last seen
+	require.NoError(t, repository.Touch(context.Background(), tests.MockApplicant.ID, session.ID))
+	sessions, err = repository.List(context.Background(), tests.MockApplicant.ID)
+	require.NoError(t, err)
+	listed, _ = findSession(sessions, session.ID)
+	require.True(t, listed.LastSeen.After(session.LastSeen))
+
+	// Sessions of other users and expired sessions are not found
+	require.ErrorIs(t, repository.Touch(context.Background(), tests.MockRecruiter.ID, session.ID), database.ErrSessionNotFound)
+	require.ErrorIs(t, repository.Revoke(context.Background(), tests.MockRecruiter.ID, session.ID), database.ErrSessionNotFound)
+	require.ErrorIs(t, repository.Touch(context.Background(), tests.MockApplicant.ID, expired.ID), database.ErrSessionNotFound)
+
+	// Revoked sessions can't be used or revoked again
+	require.NoError(t, repository.Revoke(context.Background(), tests.MockApplicant.ID, session.ID))
+	require.ErrorIs(t, repository.Touch(context.Background(), tests.MockApplicant.ID, session.ID), database.ErrSessionNotFound)
+	require.ErrorIs(t, repository.Revoke(context.Background(), tests.MockApplicant.ID, session.ID), database.ErrSessionNotFound)
+
+	sessions, err = repository.List(context.Background(), tests.MockApplicant.ID)
+	require.NoError(t, err)
+	_, ok = findSession(sessions, session.ID)
+	require.False(t, ok)
+}
@@ -1,10 +1,13 @@
 package database_test
 
 import (
+	"context"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
 	"github.com/IV1201-Group-2/login-service/tests"
 	"github.com/stretchr/testify/require"
 )
@@ -16,7 +19,7 @@ func TestQueryUser(t *testing.T) {
 	repository := database.NewUserRepository(tests.Database)
 
 	// Query for applicant
-	applicant, err := repository.Query(tests.MockApplicant.Email)
+	applicant, err := repository.Query(context.Background(), tests.MockApplicant.Email)
 	require.NoError(t, err)
 
 	require.Equal(t, tests.MockApplicant.ID, applicant.ID)
@@ -28,7 +31,7 @@ func TestQueryUser(t *testing.T) {
 	require.NotEqual(t, tests.MockPassword, applicant.Password)
 
 	// Query for recruiter
-	recruiter, err := repository.Query(tests.MockRecruiter.Username)
+	recruiter, err := repository.Query(context.Background(), tests.MockRecruiter.Username)
 	require.NoError(t, err)
 
 	require.Equal(t, tests.MockRecruiter.ID, recruiter.ID)
@@ -43,7 +46,7 @@ func TestQueryUser(t *testing.T) {
 	// NOTE: This should NOT fail with our test data. The service layer guards against information leaks.
 	// It's not possible to handle in the database layer because there are some users
 	// thave have empty username or emails.
-	user, err := repository.Query("")
+	user, err := repository.Query(context.Background(), "")
 	require.NotNil(t, user)
 	require.NoError(t, err)
 }
@@ -55,12 +58,12 @@ func TestQueryInvalidIdentity(t *testing.T) {
 	repository := database.NewUserRepository(tests.Database)
 
 	// Query for a user ID
-	user, err := repository.Query(strconv.Itoa(tests.MockApplicant.ID))
+	user, err := repository.Query(context.Background(), strconv.Itoa(tests.MockApplicant.ID))
 	require.Nil(t, user)
 	require.ErrorIs(t, err, database.ErrUserNotFound)
 
 	// Query for invalid identity
-	user, err = repository.Query("wrong")
+	user, err = repository.Query(context.Background(), "wrong")
 	require.Nil(t, user)
 	require.ErrorIs(t, err, database.ErrUserNotFound)
 }
@@ -74,15 +77,200 @@ func TestResetPassword(t *testing.T) {
 	repository := database.NewUserRepository(tests.Database)
 
 	// Query for the user once
-	user, err := repository.Query(tests.MockApplicant5.Email)
+	user, err := repository.Query(context.Background(), tests.MockApplicant5.Email)
 	require.NoError(t, err)
 	require.Empty(t, user.Password)
 
-	err = repository.UpdatePassword(tests.MockApplicant5.ID, newPassword)
+	err = repository.UpdatePassword(context.Background(), tests.MockApplicant5.ID, newPassword)
 	require.NoError(t, err)
 
 	// Query for the user again
-	user, err = repository.Query(tests.MockApplicant5.Email)
+	user, err = repository.Query(context.Background(), tests.MockApplicant5.Email)
 	require.NoError(t, err)
 	require.Equal(t, newPassword, user.Password)
 }
+
+// Test that users can be listed and searched.
+func TestListUsers(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+
+	// Search is case-insensitive and matches part of the e-mail address
+	users, total, err := repository.List(context.Background(), database.UserFilter{Search: "APPLICANT2@", Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Len(t, users, 1)
+	require.Equal(t, tests.MockApplicant2.ID, users[0].ID)
+	require.Equal(t, "Mock", users[0].Name)
+	require.False(t, users[0].HasPassword)
+
+	// Filter by role
+	role := model.RoleRecruiter
+	users, _, err = repository.List(context.Background(), database.UserFilter{Role: &role, Limit: 10})
+	require.NoError(t, err)
+	require.NotEmpty(t, users)
+	for _, user := range users {
+		require.Equal(t, model.RoleRecruiter, user.Role)
+	}
+
+	// Pagination
+	users, total, err = repository.List(context.Background(), database.UserFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Greater(t, total, 1)
+	require.Equal(t, tests.MockApplicant2.ID, users[0].ID)
+}
+
+// Test that the status of a user can be queried by ID.
+func TestUserStatus(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+
+	status, err := repository.Status(context.Background(), tests.MockApplicant.ID)
+	require.NoError(t, err)
+	require.Equal(t, tests.MockApplicant.Email, status.Email)
+	require.Equal(t, tests.MockApplicant.Role, status.Role)
+	require.True(t, status.HasPassword)
+
+	status, err = repository.Status(context.Background(), 999999)
+	require.Nil(t, status)
+	require.ErrorIs(t, err, database.ErrUserNotFound)
+}
+
+// Test that the role of a user can be changed.
+func TestUpdateRole(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+
+	require.NoError(t, repository.UpdateRole(context.Background(), tests.MockApplicant5.ID, model.RoleRecruiter))
+	status, err := repository.Status(context.Background(), tests.MockApplicant5.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.RoleRecruiter, status.Role)
+	role, disabled, err := repository.Access(context.Background(), tests.MockApplicant5.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.RoleRecruiter, role)
+	require.False(t, disabled)
+
+	require.NoError(t, repository.UpdateRole(context.Background(), tests.MockApplicant5.ID, model.RoleApplicant))
+
+	err = repository.UpdateRole(context.Background(), 999999, model.RoleApplicant)
+	require.ErrorIs(t, err, database.ErrUserNotFound)
+}
+
+// Test that disabled users are returned together with ErrUserDisabled and that their sessions are revoked.
+func TestDisableUser(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+	sessionRepository := database.NewSessionRepository(tests.Database)
+	now := time.Now().Truncate(time.Second)
+	session := model.Session{
+		ID:        "disabled-" + tests.RandomStr(16),
+		PersonID:  tests.MockApplicant7.ID,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	require.NoError(t, sessionRepository.Create(context.Background(), session))
+
+	require.NoError(t, repository.SetDisabled(context.Background(), tests.MockApplicant7.ID, true))
+
+	sessions, err := sessionRepository.List(context.Background(), tests.MockApplicant7.ID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+	err = sessionRepository.Touch(context.Background(), tests.MockApplicant7.ID, session.ID)
+	require.ErrorIs(t, err, database.ErrSessionNotFound)
+
+	user, err := repository.Query(context.Background(), tests.MockApplicant7.Email)
+	require.ErrorIs(t, err, database.ErrUserDisabled)
+	require.Equal(t, tests.MockApplicant7.ID, user.ID)
+
+	disabled, err := repository.Disabled(context.Background(), tests.MockApplicant7.ID)
+	require.NoError(t, err)
+	require.True(t, disabled)
+
+	status, err := repository.Status(context.Background(), tests.MockApplicant7.ID)
+	require.NoError(t, err)
+	require.True(t, status.Disabled)
+
+	require.NoError(t, repository.SetDisabled(context.Background(), tests.MockApplicant7.ID, false))
+	_, err = repository.Query(context.Background(), tests.MockApplicant7.Email)
+	require.NoError(t, err)
+	// Enabling the account doesn't bring back revoked sessions
+	sessions, err = sessionRepository.List(context.Background(), tests.MockApplicant7.ID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	_, err = repository.Disabled(context.Background(), 999999)
+	require.ErrorIs(t, err, database.ErrUserNotFound)
+	err = repository.SetDisabled(context.Background(), 999999, true)
+	require.ErrorIs(t, err, database.ErrUserNotFound)
+}
+
+// Test that created users have an unverified e-mail address and a unique identity.
+func TestCreateUser(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+	registration := model.Registration{
+		Name:           "Created",
+		Surname:        "Applicant",
+		PersonalNumber: "199505054446",
+		Email:          "mockuser-created@example.com",
+		Password:       tests.MockPasswordBcrypt,
+	}
+
+	id, err := repository.Create(context.Background(), registration)
+	require.NoError(t, err)
+
+	user, err := repository.Query(context.Background(), registration.Email)
+	require.ErrorIs(t, err, database.ErrEmailUnverified)
+	require.Equal(t, id, user.ID)
+	require.Equal(t, model.RoleApplicant, user.Role)
+
+	// E-mail addresses are compared case-insensitively, also against usernames
+	registration.Email = "MOCKUSER-CREATED@example.com"
+	registration.PersonalNumber = "199001011247"
+	_, err = repository.Create(context.Background(), registration)
+	require.ErrorIs(t, err, database.ErrEmailTaken)
+	require.NotErrorIs(t, err, database.ErrPersonalNumberTaken)
+
+	registration.Email = tests.MockRecruiter.Username
+	_, err = repository.Create(context.Background(), registration)
+	require.ErrorIs(t, err, database.ErrEmailTaken)
+
+	// Verification only succeeds for the current address
+	require.ErrorIs(t, repository.VerifyEmail(context.Background(), id, "mockuser-other@example.com"), database.ErrUserNotFound)
+	require.NoError(t, repository.VerifyEmail(context.Background(), id, "mockuser-created@example.com"))
+	_, err = repository.Query(context.Background(), "mockuser-created@example.com")
+	require.NoError(t, err)
+}
+
+// Tests that e-mail addresses can only be changed to addresses that don't identify other users.
+func TestChangeEmail(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+	id := tests.MockApplicant9.ID
+	email := "mockuser-changed9@example.com"
+
+	// The user's own address is not taken, but the identities of other users are
+	require.NoError(t, repository.EmailAvailable(context.Background(), id, "MOCKUSER-APPLICANT9@example.com"))
+	require.ErrorIs(t, repository.EmailAvailable(context.Background(), id, tests.MockApplicant.Email), database.ErrEmailTaken)
+	require.ErrorIs(t, repository.EmailAvailable(context.Background(), id, tests.MockRecruiter.Username), database.ErrEmailTaken)
+	require.ErrorIs(t, repository.ChangeEmail(context.Background(), id, tests.MockApplicant9.Email, tests.MockApplicant.Email), database.ErrEmailTaken)
+
+	// The change only applies if the user still has the address that the link was sent from
+	err := repository.ChangeEmail(context.Background(), id, "mockuser-other@example.com", email)
+	require.ErrorIs(t, err, database.ErrUserNotFound)
+
+	require.NoError(t, repository.ChangeEmail(context.Background(), id, tests.MockApplicant9.Email, email))
+	user, err := repository.Query(context.Background(), email)
+	require.NoError(t, err)
+	require.Equal(t, id, user.ID)
+	_, err = repository.Query(context.Background(), tests.MockApplicant9.Email)
+	require.ErrorIs(t, err, database.ErrUserNotFound)
+}
@@ -0,0 +1,190 @@
+package logging_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/stretchr/testify/require"
+)
+
+// Reads and decompresses a log backup.
+func readBackup(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(content)
+}
+
+// Test that the log file is rotated by size and that old backups are pruned.
+func TestFileRotatesBySize(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "service.log")
+	file, err := logging.OpenFile(logging.FileOptions{Path: path, MaxSize: 16, MaxBackups: 2})
+	require.NoError(t, err)
+
+	lines := []string{"first line\n", "second line\n", "third line\n", "fourth line\n"}
+	for _, line := range lines {
+		_, err := file.Write([]byte(line))
+		require.NoError(t, err)
+		// Make sure every backup gets a unique timestamp
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, file.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, lines[3], string(content))
+
+	backups := file.Backups()
+	require.Len(t, backups, 2)
+	require.Equal(t, lines[1], readBackup(t, backups[0]))
+	require.Equal(t, lines[2], readBackup(t, backups[1]))
+}
+
+// Test that files that only look like backups are not counted or pruned.
+func TestFileBackupsPattern(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service.log")
+	for _, name := range []string{"service-old.log.gz", "service-api-20240301T120000.000.log.gz", "service-20240301T120000.000.log.gz"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+
+	file, err := logging.OpenFile(logging.FileOptions{Path: path, MaxSize: 16, MaxBackups: 1})
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "service-20240301T120000.000.log.gz")}, file.Backups())
+
+	_, err = file.Write([]byte("first line\n"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("second line\n"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// The old backup is pruned, but the other files are kept
+	require.NoFileExists(t, filepath.Join(dir, "service-20240301T120000.000.log.gz"))
+	require.FileExists(t, filepath.Join(dir, "service-old.log.gz"))
+	require.FileExists(t, filepath.Join(dir, "service-api-20240301T120000.000.log.gz"))
+	require.Len(t, file.Backups(), 1)
+}
+
+// Test that the file can still be written to if it can't be rotated.
+func TestFileRotateError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "service.log")
+	file, err := logging.OpenFile(logging.FileOptions{Path: path, MaxSize: 16})
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	// The file can't be renamed once it has been removed
+	require.NoError(t, os.Remove(path))
+	for _, line := range []string{"first line\n", "second line\n", "third line\n"} {
+		_, err = file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.Empty(t, file.Backups())
+
+	// Once the file is back, it is rotated again
+	require.NoError(t, file.Reopen())
+	_, err = file.Write([]byte("fourth line\n"))
+	require.NoError(t, err)
+	_, err = file.Write([]byte("fifth line\n"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.Len(t, file.Backups(), 1)
+}
+
+// Test that the log file is rotated once it gets too old.
+func TestFileRotatesByAge(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "service.log")
+	file, err := logging.OpenFile(logging.FileOptions{Path: path, MaxAge: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	_, err = file.Write([]byte("old line\n"))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	_, err = file.Write([]byte("new line\n"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "new line\n", string(content))
+
+	backups := file.Backups()
+	require.Len(t, backups, 1)
+	require.Equal(t, "old line\n", readBackup(t, backups[0]))
+}
+
+// Test that the log file is recreated after it has been moved by an external tool.
+func TestFileReopen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service.log")
+	file, err := logging.OpenFile(logging.FileOptions{Path: path})
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.Write([]byte("before\n"))
+	require.NoError(t, err)
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "service.log.1")))
+
+	require.NoError(t, file.Reopen())
+	_, err = file.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "after\n", string(content))
+
+	moved, err := os.ReadFile(filepath.Join(dir, "service.log.1"))
+	require.NoError(t, err)
+	require.Equal(t, "before\n", string(moved))
+}
+
+// Test that existing logs are appended to instead of being overwritten.
+func TestFileAppends(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "service.log")
+	require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o600))
+
+	file, err := logging.OpenFile(logging.FileOptions{Path: path})
+	require.NoError(t, err)
+	_, err = file.Write([]byte("appended\n"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "existing\nappended\n", string(content))
+}
+
+// Test that a log file that can't be opened is reported as an error.
+func TestFileOpenError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "missing", "service.log")
+	_, err := logging.OpenFile(logging.FileOptions{Path: path})
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), "open log file"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
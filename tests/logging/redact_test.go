@@ -0,0 +1,151 @@
+package logging_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// Captures everything logged by fn with the specified redaction mode.
+// Tests using this function can't run in parallel since they change the global logger.
+func captureLogs(t *testing.T, mode string, fn func()) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logging.SetOutput(&buf)
+	require.NoError(t, logging.SetRedaction(mode, []byte("testkey")))
+	defer func() {
+		logging.SetOutput(os.Stdout)
+		require.NoError(t, logging.SetRedaction(logging.RedactOff, nil))
+	}()
+
+	fn()
+	return buf.String()
+}
+
+// Creates an Echo context for a request from the specified IP.
+func newContext(ip string) echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+	req.RemoteAddr = ip + ":1234"
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+// Tests that personal information is logged as-is when redaction is off.
+func TestRedactOff(t *testing.T) {
+	logs := captureLogs(t, logging.RedactOff, func() {
+		logging.Logf(logrus.InfoLevel, "user 'someone@example.com' from 192.0.2.10")
+	})
+
+	require.Contains(t, logs, "someone@example.com")
+	require.Contains(t, logs, "192.0.2.10")
+}
+
+// Tests that e-mail addresses and IP addresses are masked.
+func TestRedactMask(t *testing.T) {
+	logs := captureLogs(t, logging.RedactMask, func() {
+		logging.Logf(logrus.InfoLevel, "user 'someone@example.com' from 192.0.2.10 and 2001:db8::1")
+	})
+
+	require.NotContains(t, logs, "someone@example.com")
+	require.Contains(t, logs, "s***@example.com")
+	require.NotContains(t, logs, "192.0.2.10")
+	require.Contains(t, logs, "192.0.2.x")
+	require.NotContains(t, logs, "2001:db8::1")
+}
+
+// Tests that IP addresses followed by a port are masked and the port is kept.
+func TestRedactMaskAddress(t *testing.T) {
+	cases := map[string]string{
+		"10.0.0.1:54321":        "10.0.0.x:54321",
+		"[::1]:8080":            "[::x]:8080",
+		"[2001:db8::1]:443":     "[2001:db8::x]:443",
+		"[2001:db8::1]":         "[2001:db8::x]",
+		"192.0.2.10:80 and foo": "192.0.2.x:80 and foo",
+	}
+
+	for address, expected := range cases {
+		logs := captureLogs(t, logging.RedactMask, func() {
+			logging.Logf(logrus.InfoLevel, "connection from %s", address)
+		})
+
+		require.Contains(t, logs, "connection from "+expected, address)
+	}
+
+	// Times and other values with colons are left alone
+	logs := captureLogs(t, logging.RedactMask, func() {
+		logging.Logf(logrus.InfoLevel, "retrying at 16:37 in 1.5s")
+	})
+	require.Contains(t, logs, "retrying at 16:37 in 1.5s")
+}
+
+// Tests that personal information is replaced with a keyed hash that is the same on every line.
+func TestRedactHash(t *testing.T) {
+	logs := captureLogs(t, logging.RedactHash, func() {
+		logging.Logf(logrus.InfoLevel, "first 'someone@example.com'")
+		logging.Logf(logrus.InfoLevel, "second 'someone@example.com'")
+		logging.Logf(logrus.InfoLevel, "third 'other@example.com'")
+	})
+
+	require.NotContains(t, logs, "someone@example.com")
+	require.NotContains(t, logs, "other@example.com")
+
+	hashes := regexp.MustCompile(`pii:[0-9a-f]+`).FindAllString(logs, -1)
+	require.Len(t, hashes, 3)
+	require.Equal(t, hashes[0], hashes[1])
+	require.NotEqual(t, hashes[0], hashes[2])
+}
+
+// Tests that the identity submitted in a request is redacted even if it isn't an e-mail address.
+func TestRedactIdentity(t *testing.T) {
+	c := newContext("198.51.100.7")
+	logging.SetIdentity(c, "mockuser_recruiter")
+
+	logs := captureLogs(t, logging.RedactMask, func() {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: wrong password for user '%s'", "mockuser_recruiter")
+	})
+
+	require.NotContains(t, logs, "mockuser_recruiter")
+	require.Contains(t, logs, "m***")
+	require.NotContains(t, logs, "198.51.100.7")
+}
+
+// Tests that a short identity is only redacted where it is logged, not wherever its characters appear.
+func TestRedactShortIdentity(t *testing.T) {
+	c := newContext("198.51.100.7")
+	logging.SetIdentity(c, "a")
+
+	logs := captureLogs(t, logging.RedactMask, func() {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: wrong password for user '%s'", "a")
+	})
+
+	require.Contains(t, logs, "Unauthorized attempt: wrong password for user 'a***'")
+}
+
+// Tests that masked e-mail addresses keep only the domain after the last "@".
+func TestRedactMaskLastAt(t *testing.T) {
+	c := newContext("198.51.100.7")
+	logging.SetIdentity(c, "first@second@example.com")
+
+	logs := captureLogs(t, logging.RedactMask, func() {
+		logging.Logcf(logrus.WarnLevel, c, "Unauthorized attempt: user '%s' not found", "first@second@example.com")
+		logging.Logf(logrus.InfoLevel, "user '%s'", "some%one@example.com")
+	})
+
+	require.Contains(t, logs, "user 'f***@example.com' not found")
+	require.NotContains(t, logs, "second")
+	require.Contains(t, logs, "user 's***@example.com'")
+	require.NotContains(t, logs, "one@")
+}
+
+// Tests that an unknown redaction mode is rejected.
+func TestRedactUnknownMode(t *testing.T) {
+	require.ErrorIs(t, logging.SetRedaction("wrong", nil), logging.ErrUnknownRedaction)
+}
@@ -161,3 +161,18 @@ INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (3, 'Mock', 'Applicant 4', '20
 INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (4, 'Mock', 'Applicant 5', '200001015555', 'mockuser-applicant5@example.com', '', 2, '');
 -- Recruiter (login: mockuser_recruiter, password)
 INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (5, 'Mock', 'Recruiter', '200001016666', '', '$2a$10$c4WCXRkTtYb3fJ7Wpnjok.nhrEcFyxqpJ/mjfAjBDzqW1IWT6EjVi', 1, 'mockuser_recruiter');
+-- Applicant with password (login: mockuser-applicant6@example.com, password)
+INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (6, 'Mock', 'Applicant 6', '200001017777', 'mockuser-applicant6@example.com', '$2a$10$c4WCXRkTtYb3fJ7Wpnjok.nhrEcFyxqpJ/mjfAjBDzqW1IWT6EjVi', 2, '');
+-- Applicant without password that is disabled during the tests (login: mockuser-applicant7@example.com)
+INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (7, 'Mock', 'Applicant 7', '200001018888', 'mockuser-applicant7@example.com', '', 2, '');
+-- Recruiter that is disabled during the tests (login: mockuser_recruiter2, password)
+INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (8, 'Mock', 'Recruiter 2', '200001019999', '', '$2a$10$c4WCXRkTtYb3fJ7Wpnjok.nhrEcFyxqpJ/mjfAjBDzqW1IWT6EjVi', 1, 'mockuser_recruiter2');
+-- Applicant whose password is changed during the tests (login: mockuser_applicant8, password)
+INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (9, 'Mock', 'Applicant 8', '200001010000', 'mockuser-applicant8@example.com', '$2a$10$c4WCXRkTtYb3fJ7Wpnjok.nhrEcFyxqpJ/mjfAjBDzqW1IWT6EjVi', 2, 'mockuser_applicant8');
+-- Applicant whose e-mail address is changed during the tests (login: mockuser-applicant9@example.com, password)
+INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (10, 'Mock', 'Applicant 9', '200001011212', 'mockuser-applicant9@example.com', '$2a$10$c4WCXRkTtYb3fJ7Wpnjok.nhrEcFyxqpJ/mjfAjBDzqW1IWT6EjVi', 2, '');
+-- Applicant that is locked out during the tests (login: mockuser-applicant10@example.com, password)
+INSERT INTO person OVERRIDING SYSTEM VALUE VALUES (11, 'Mock', 'Applicant 10', '200001011414', 'mockuser-applicant10@example.com', '$2a$10$c4WCXRkTtYb3fJ7Wpnjok.nhrEcFyxqpJ/mjfAjBDzqW1IWT6EjVi', 2, '');
+
+-- Users created by the tests are numbered after the mock users
+SELECT setval('person_person_id_seq', (SELECT max(person_id) FROM person));
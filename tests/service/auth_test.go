@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"context"
 	"strconv"
 	"testing"
 
@@ -18,7 +19,7 @@ func TestAuthenticateUser(t *testing.T) {
 	repository := database.NewUserRepository(tests.Database)
 
 	// Authenticate as applicant
-	user, err := service.AuthenticateUser(repository, tests.MockApplicant.Email, tests.MockPassword, &tests.MockApplicant.Role)
+	user, err := service.AuthenticateUser(context.Background(), repository, tests.MockApplicant.Email, tests.MockPassword, &tests.MockApplicant.Role)
 	require.NoError(t, err)
 	require.Equal(t, tests.MockApplicant.ID, user.ID)
 	require.Equal(t, tests.MockApplicant.Email, user.Email)
@@ -26,7 +27,7 @@ func TestAuthenticateUser(t *testing.T) {
 	require.Equal(t, tests.MockApplicant.Role, user.Role)
 
 	// Authenticate as recruiter
-	user, err = service.AuthenticateUser(repository, tests.MockRecruiter.Username, tests.MockPassword, &tests.MockRecruiter.Role)
+	user, err = service.AuthenticateUser(context.Background(), repository, tests.MockRecruiter.Username, tests.MockPassword, &tests.MockRecruiter.Role)
 	require.NoError(t, err)
 	require.Equal(t, tests.MockRecruiter.ID, user.ID)
 	require.Equal(t, tests.MockRecruiter.Email, user.Email)
@@ -41,19 +42,19 @@ func TestAuthenticateWrongIdentity(t *testing.T) {
 	repository := database.NewUserRepository(tests.Database)
 
 	// Authenticate using empty identity
-	_, err := service.AuthenticateUser(repository, "", tests.MockPassword, &tests.MockApplicant.Role)
+	_, err := service.AuthenticateUser(context.Background(), repository, "", tests.MockPassword, &tests.MockApplicant.Role)
 	require.ErrorIs(t, err, service.ErrWrongIdentity)
 
 	// Authenticate using the wrong identity
-	_, err = service.AuthenticateUser(repository, "wrong", tests.MockPassword, &tests.MockApplicant.Role)
+	_, err = service.AuthenticateUser(context.Background(), repository, "wrong", tests.MockPassword, &tests.MockApplicant.Role)
 	require.ErrorIs(t, err, service.ErrWrongIdentity)
 
 	// Authenticate using the user's ID
-	_, err = service.AuthenticateUser(repository, strconv.Itoa(tests.MockApplicant.ID), tests.MockPassword, &tests.MockApplicant.Role)
+	_, err = service.AuthenticateUser(context.Background(), repository, strconv.Itoa(tests.MockApplicant.ID), tests.MockPassword, &tests.MockApplicant.Role)
 	require.ErrorIs(t, err, service.ErrWrongIdentity)
 
 	// Authenticate using the wrong role
-	_, err = service.AuthenticateUser(repository, tests.MockApplicant.Email, tests.MockPassword, &tests.MockRecruiter.Role)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant.Email, tests.MockPassword, &tests.MockRecruiter.Role)
 	require.ErrorIs(t, err, service.ErrWrongIdentity)
 }
 
@@ -64,13 +65,38 @@ func TestAuthenticateWrongPassword(t *testing.T) {
 	repository := database.NewUserRepository(tests.Database)
 
 	// Authenticate using the wrong password
-	_, err := service.AuthenticateUser(repository, tests.MockApplicant.Email, "wrong", &tests.MockApplicant.Role)
+	_, err := service.AuthenticateUser(context.Background(), repository, tests.MockApplicant.Email, "wrong", &tests.MockApplicant.Role)
 	require.ErrorIs(t, err, service.ErrWrongPassword)
 	// Authenticate using the user's hashed password
-	_, err = service.AuthenticateUser(repository, tests.MockApplicant.Email, tests.MockApplicant.Password, &tests.MockApplicant.Role)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant.Email, tests.MockApplicant.Password, &tests.MockApplicant.Role)
 	require.ErrorIs(t, err, service.ErrWrongPassword)
 }
 
+// Tests that disabled users can't log in, receive a reset token or verify their e-mail address.
+func TestAuthenticateDisabled(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+	require.NoError(t, repository.SetDisabled(context.Background(), tests.MockRecruiter2.ID, true))
+	require.NoError(t, repository.SetDisabled(context.Background(), tests.MockApplicant7.ID, true))
+
+	// The correct password reveals that the account is disabled
+	user, err := service.AuthenticateUser(context.Background(), repository, tests.MockRecruiter2.Username, tests.MockPassword, nil)
+	require.ErrorIs(t, err, service.ErrUserDisabled)
+	require.Equal(t, tests.MockRecruiter2.ID, user.ID)
+	// The wrong password doesn't
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockRecruiter2.Username, "wrong", nil)
+	require.ErrorIs(t, err, service.ErrWrongPassword)
+	// Users without a password are disabled instead of missing a password
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant7.Email, "", nil)
+	require.ErrorIs(t, err, service.ErrUserDisabled)
+	// Verification links stop working too
+	claims := model.UserClaims{User: tests.MockApplicant7}
+	claims.Usage = model.TokenUsageVerify
+	_, err = service.VerifyEmail(context.Background(), repository, claims)
+	require.ErrorIs(t, err, service.ErrUserDisabled)
+}
+
 // Tests that resetting the password of a user works and the user is modified in the repository.
 func TestResetPassword(t *testing.T) {
 	t.Parallel()
@@ -88,14 +114,14 @@ func TestResetPassword(t *testing.T) {
 	}
 
 	// Try before password reset
-	_, err := service.AuthenticateUser(repository, tests.MockApplicant4.Email, newPassword, &tests.MockApplicant4.Role)
+	_, err := service.AuthenticateUser(context.Background(), repository, tests.MockApplicant4.Email, newPassword, &tests.MockApplicant4.Role)
 	require.ErrorIs(t, err, service.ErrMissingPassword)
 
-	err = service.UpdatePassword(repository, claims, newPassword)
+	err = service.UpdatePassword(context.Background(), repository, claims, newPassword)
 	require.NoError(t, err)
 
 	// Try again after password reset
-	_, err = service.AuthenticateUser(repository, tests.MockApplicant4.Email, newPassword, &tests.MockApplicant4.Role)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant4.Email, newPassword, &tests.MockApplicant4.Role)
 	require.NoError(t, err)
 }
 
@@ -115,6 +141,34 @@ func TestResetWrongUsage(t *testing.T) {
 		User: tests.MockApplicant4,
 	}
 
-	err := service.UpdatePassword(repository, claims, newPassword)
+	err := service.UpdatePassword(context.Background(), repository, claims, newPassword)
+	require.ErrorIs(t, err, service.ErrWrongUsage)
+}
+
+// Tests that logged in users can change their password if they know the current one.
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+	claims := model.UserClaims{User: tests.MockApplicant8}
+	claims.Usage = model.TokenUsageLogin
+
+	// Change password with the wrong current password
+	_, err := service.ChangePassword(context.Background(), repository, claims, "wrong", "newpassword")
+	require.ErrorIs(t, err, service.ErrWrongPassword)
+
+	user, err := service.ChangePassword(context.Background(), repository, claims, tests.MockPassword, "newpassword")
+	require.NoError(t, err)
+	require.Equal(t, tests.MockApplicant8.ID, user.ID)
+
+	// Only the new password works
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant8.Username, "newpassword", nil)
+	require.NoError(t, err)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant8.Username, tests.MockPassword, nil)
+	require.ErrorIs(t, err, service.ErrWrongPassword)
+
+	// Reset tokens can't be used
+	claims.Usage = model.TokenUsageReset
+	_, err = service.ChangePassword(context.Background(), repository, claims, "newpassword", tests.MockPassword)
 	require.ErrorIs(t, err, service.ErrWrongUsage)
 }
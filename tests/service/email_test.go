@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that the confirmation link is sent to the new address and a notice to the current one.
+func TestSendEmailChange(t *testing.T) {
+	t.Parallel()
+
+	mailer := &recordingMailer{}
+	email := "mockuser-new@example.com"
+	err := service.SendEmailChange(context.Background(), mailer, tests.MockApplicant, email, []byte(tests.MockSecret), "https://example.com/verify")
+	require.NoError(t, err)
+
+	require.Len(t, mailer.messages, 2)
+	require.Equal(t, email, mailer.messages[0].To)
+	require.Equal(t, tests.MockApplicant.Email, mailer.messages[1].To)
+	require.Contains(t, mailer.messages[1].Body, email)
+	require.NotContains(t, mailer.messages[1].Body, "token=")
+
+	link, err := url.Parse(regexp.MustCompile(`https://\S+`).FindString(mailer.messages[0].Body))
+	require.NoError(t, err)
+
+	claims, err := service.ParseToken(context.Background(), link.Query().Get("token"), []byte(tests.MockSecret))
+	require.NoError(t, err)
+	require.Equal(t, model.TokenUsageEmailChange, claims.Usage)
+	require.Equal(t, tests.MockApplicant.Email, claims.User.Email)
+	require.Equal(t, email, claims.NewEmail)
+
+	// Users without an e-mail address only receive the link
+	mailer = &recordingMailer{}
+	err = service.SendEmailChange(context.Background(), mailer, tests.MockRecruiter, email, []byte(tests.MockSecret), "https://example.com/verify")
+	require.NoError(t, err)
+	require.Len(t, mailer.messages, 1)
+}
+
+// Tests that the reset link is mailed to the user's own address.
+func TestSendPasswordReset(t *testing.T) {
+	t.Parallel()
+
+	mailer := &recordingMailer{}
+	err := service.SendPasswordReset(context.Background(), mailer, tests.MockApplicant, []byte(tests.MockSecret), "https://example.com/reset")
+	require.NoError(t, err)
+
+	require.Len(t, mailer.messages, 1)
+	require.Equal(t, tests.MockApplicant.Email, mailer.messages[0].To)
+
+	link, err := url.Parse(regexp.MustCompile(`https://\S+`).FindString(mailer.messages[0].Body))
+	require.NoError(t, err)
+	require.Equal(t, "/reset", link.Path)
+
+	claims, err := service.ParseToken(context.Background(), link.Query().Get("token"), []byte(tests.MockSecret))
+	require.NoError(t, err)
+	require.Equal(t, model.TokenUsageReset, claims.Usage)
+	require.Equal(t, tests.MockApplicant.ID, claims.User.ID)
+
+	// Users without an e-mail address can't be sent a link
+	mailer = &recordingMailer{}
+	err = service.SendPasswordReset(context.Background(), mailer, tests.MockRecruiter, []byte(tests.MockSecret), "https://example.com/reset")
+	require.ErrorIs(t, err, service.ErrNoEmail)
+	require.Empty(t, mailer.messages)
+}
+
+// Tests that a logged in user can change their e-mail address once they confirm the new address.
+func TestChangeEmail(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+	email := "mockuser-changed9@example.com"
+
+	token := model.UserClaims{User: tests.MockApplicant9}
+	token.Usage = model.TokenUsageLogin
+
+	_, err := service.RequestEmailChange(context.Background(), repository, token, "wrong", email)
+	require.ErrorIs(t, err, service.ErrWrongPassword)
+	_, err = service.RequestEmailChange(context.Background(), repository, token, tests.MockPassword, tests.MockApplicant.Email)
+	require.ErrorIs(t, err, database.ErrEmailTaken)
+
+	user, err := service.RequestEmailChange(context.Background(), repository, token, tests.MockPassword, email)
+	require.NoError(t, err)
+	require.Equal(t, tests.MockApplicant9.Email, user.Email)
+
+	// The address is changed once the link is opened
+	claims := model.UserClaims{User: *user}
+	claims.Usage = model.TokenUsageEmailChange
+	claims.NewEmail = email
+	changed, err := service.VerifyEmail(context.Background(), repository, claims)
+	require.NoError(t, err)
+	require.Equal(t, email, changed.Email)
+
+	_, err = service.AuthenticateUser(context.Background(), repository, email, tests.MockPassword, nil)
+	require.NoError(t, err)
+	_, err = service.AuthenticateUser(context.Background(), repository, tests.MockApplicant9.Email, tests.MockPassword, nil)
+	require.ErrorIs(t, err, service.ErrWrongIdentity)
+
+	// The link can only be used once
+	_, err = service.VerifyEmail(context.Background(), repository, claims)
+	require.ErrorIs(t, err, database.ErrUserNotFound)
+
+	// Reset tokens can't be used to change the address
+	token.Usage = model.TokenUsageReset
+	_, err = service.RequestEmailChange(context.Background(), repository, token, tests.MockPassword, email)
+	require.ErrorIs(t, err, service.ErrWrongUsage)
+}
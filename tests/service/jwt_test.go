@@ -1,7 +1,7 @@
 package service_test
 
 import (
-	"os"
+	"context"
 	"testing"
 
 	"github.com/IV1201-Group-2/login-service/model"
@@ -15,7 +15,7 @@ import (
 func TestSignLoginToken(t *testing.T) {
 	t.Parallel()
 
-	token, expiry, err := service.SignUserToken(tests.MockApplicant, []byte(os.Getenv("JWT_SECRET")))
+	token, expiry, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
 	require.NoError(t, err)
 
 	claims := model.UserClaims{}
@@ -34,7 +34,7 @@ func TestSignLoginToken(t *testing.T) {
 func TestSignResetToken(t *testing.T) {
 	t.Parallel()
 
-	token, expiry, err := service.SignResetToken(tests.MockApplicant, []byte(os.Getenv("JWT_SECRET")))
+	token, expiry, err := service.SignResetToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
 	require.NoError(t, err)
 
 	claims := model.UserClaims{}
@@ -53,9 +53,9 @@ func TestSignResetToken(t *testing.T) {
 func TestSignHS256(t *testing.T) {
 	t.Parallel()
 
-	token1, _, err := service.SignUserToken(tests.MockApplicant, []byte(os.Getenv("JWT_SECRET")))
+	token1, _, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
 	require.NoError(t, err)
-	token2, _, err := service.SignUserToken(tests.MockApplicant, []byte(os.Getenv("JWT_SECRET")))
+	token2, _, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte(tests.MockSecret))
 	require.NoError(t, err)
 
 	claims := model.UserClaims{}
@@ -73,7 +73,7 @@ func TestSignHS256(t *testing.T) {
 func TestSignWrongSecret(t *testing.T) {
 	t.Parallel()
 
-	token, _, err := service.SignUserToken(tests.MockApplicant, []byte("wrong"))
+	token, _, err := service.SignUserToken(context.Background(), tests.MockApplicant, []byte("wrong"))
 	require.NoError(t, err)
 
 	claims := model.UserClaims{}
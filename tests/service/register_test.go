@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/mail"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Mailer that keeps every message instead of sending it.
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, message mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Tests that personal numbers are checked and normalized.
+func TestNormalizePersonalNumber(t *testing.T) {
+	t.Parallel()
+
+	valid := map[string]string{
+		"199001011239":  "199001011239",
+		"19900101-1239": "199001011239",
+		"20000229-3215": "200002293215",
+		// Coordination number
+		"199001611236": "199001611236",
+	}
+	for number, expected := range valid {
+		normalized, ok := service.NormalizePersonalNumber(number)
+		require.True(t, ok, number)
+		require.Equal(t, expected, normalized)
+	}
+
+	for _, number := range []string{"", "9001011239", "199001011238", "19900230-1233", "1990-01-01-1239", "19900101123x"} {
+		_, ok := service.NormalizePersonalNumber(number)
+		require.False(t, ok, number)
+	}
+}
+
+// Tests that the notice about an existing account contains no link or token.
+func TestSendRegistrationNotice(t *testing.T) {
+	t.Parallel()
+
+	mailer := &recordingMailer{}
+	require.NoError(t, service.SendRegistrationNotice(context.Background(), mailer, tests.MockApplicant.Email))
+
+	require.Len(t, mailer.messages, 1)
+	require.Equal(t, tests.MockApplicant.Email, mailer.messages[0].To)
+	require.NotContains(t, mailer.messages[0].Body, "http")
+}
+
+// Tests that verification links contain a verification token for the user.
+func TestSendVerification(t *testing.T) {
+	t.Parallel()
+
+	mailer := &recordingMailer{}
+	err := service.SendVerification(context.Background(), mailer, tests.MockApplicant, []byte(tests.MockSecret), "https://example.com/verify?lang=sv")
+	require.NoError(t, err)
+
+	require.Len(t, mailer.messages, 1)
+	require.Equal(t, tests.MockApplicant.Email, mailer.messages[0].To)
+
+	link, err := url.Parse(regexp.MustCompile(`https://\S+`).FindString(mailer.messages[0].Body))
+	require.NoError(t, err)
+	require.Equal(t, "example.com", link.Host)
+	require.Equal(t, "sv", link.Query().Get("lang"))
+
+	claims, err := service.ParseToken(context.Background(), link.Query().Get("token"), []byte(tests.MockSecret))
+	require.NoError(t, err)
+	require.Equal(t, model.TokenUsageVerify, claims.Usage)
+	require.Equal(t, tests.MockApplicant.ID, claims.User.ID)
+	require.Equal(t, tests.MockApplicant.Email, claims.User.Email)
+
+	_, err = service.ParseToken(context.Background(), link.Query().Get("token"), []byte("wrong secret"))
+	require.ErrorIs(t, err, service.ErrJWTError)
+}
+
+// Tests that registered applicants can log in once they have verified their e-mail address.
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewUserRepository(tests.Database)
+	registration := model.Registration{
+		Name:           "Registered",
+		Surname:        "Applicant",
+		PersonalNumber: "198808088887",
+		Email:          "mockuser-registered@example.com",
+		Password:       "newpassword",
+	}
+
+	user, err := service.Register(context.Background(), repository, registration)
+	require.NoError(t, err)
+	require.Equal(t, model.RoleApplicant, user.Role)
+
+	// The same identity can't be registered twice
+	_, err = service.Register(context.Background(), repository, registration)
+	require.ErrorIs(t, err, database.ErrEmailTaken)
+	require.ErrorIs(t, err, database.ErrPersonalNumberTaken)
+
+	_, err = service.AuthenticateUser(context.Background(), repository, registration.Email, registration.Password, nil)
+	require.ErrorIs(t, err, service.ErrEmailUnverified)
+
+	claims := model.UserClaims{User: *user}
+	claims.Usage = model.TokenUsageVerify
+	verified, err := service.VerifyEmail(context.Background(), repository, claims)
+	require.NoError(t, err)
+	require.Equal(t, registration.Email, verified.Email)
+
+	authenticated, err := service.AuthenticateUser(context.Background(), repository, registration.Email, registration.Password, nil)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, authenticated.ID)
+
+	// Other tokens can't verify e-mail addresses
+	claims.Usage = model.TokenUsageLogin
+	_, err = service.VerifyEmail(context.Background(), repository, claims)
+	require.ErrorIs(t, err, service.ErrWrongUsage)
+}
@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IV1201-Group-2/login-service/database"
+	"github.com/IV1201-Group-2/login-service/model"
+	"github.com/IV1201-Group-2/login-service/service"
+	"github.com/IV1201-Group-2/login-service/tests"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests that devices are described by their browser and operating system.
+func TestDeviceLabel(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0":                                                        "Firefox on Windows",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15":                   "Safari on macOS",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0":           "Edge on Windows",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36":                   "Chrome on Android",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"curl/8.5.0": "curl",
+		"":           "Unknown device",
+	}
+	for userAgent, expected := range labels {
+		require.Equal(t, expected, service.DeviceLabel(userAgent), userAgent)
+	}
+}
+
+// Tests that login tokens belong to a session that can be listed and revoked.
+func TestStartSession(t *testing.T) {
+	t.Parallel()
+
+	repository := database.NewSessionRepository(tests.Database)
+	userAgent := "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+
+	token, expiry, err := service.StartSession(context.Background(), repository, tests.MockRecruiter, userAgent, "192.0.2.2", []byte(tests.MockSecret))
+	require.NoError(t, err)
+
+	claims, err := service.ParseToken(context.Background(), token, []byte(tests.MockSecret))
+	require.NoError(t, err)
+	require.Equal(t, model.TokenUsageLogin, claims.Usage)
+	require.Equal(t, tests.MockRecruiter.ID, claims.User.ID)
+	require.NotEmpty(t, claims.SessionID)
+	require.True(t, expiry.Equal(claims.ExpiresAt.Time))
+
+	sessions, err := service.ListSessions(context.Background(), repository, *claims)
+	require.NoError(t, err)
+	var current *model.Session
+	for i := range sessions {
+		if sessions[i].Current {
+			current = &sessions[i]
+		}
+	}
+	require.NotNil(t, current)
+	require.Equal(t, claims.SessionID, current.ID)
+	require.Equal(t, "Firefox on Linux", current.Device)
+	require.Equal(t, "192.0.2.2", current.IP)
+	require.True(t, expiry.Equal(current.ExpiresAt))
+
+	require.NoError(t, service.RevokeSession(context.Background(), repository, *claims, claims.SessionID))
+	err = service.RevokeSession(context.Background(), repository, *claims, claims.SessionID)
+	require.ErrorIs(t, err, database.ErrSessionNotFound)
+
+	// Reset tokens can't be used to manage sessions
+	claims.Usage = model.TokenUsageReset
+	_, err = service.ListSessions(context.Background(), repository, *claims)
+	require.ErrorIs(t, err, service.ErrWrongUsage)
+}
@@ -74,3 +74,69 @@ var MockRecruiter = model.User{
 	Email:    "",
 	Password: MockPasswordBcrypt, // password
 }
+
+// MockApplicant6 is an example user with role "applicant".
+// The role of this user is changed during the user management tests.
+var MockApplicant6 = model.User{
+	ID:   6,
+	Role: model.RoleApplicant,
+
+	Username: "",
+	Email:    "mockuser-applicant6@example.com",
+	Password: MockPasswordBcrypt, // password
+}
+
+// MockApplicant7 is an example user with role "applicant" and a missing password.
+// This user is disabled during the tests.
+var MockApplicant7 = model.User{
+	ID:   7,
+	Role: model.RoleApplicant,
+
+	Username: "",
+	Email:    "mockuser-applicant7@example.com",
+	Password: "",
+}
+
+// MockRecruiter2 is an example user with role "recruiter".
+// This user is disabled during the tests.
+var MockRecruiter2 = model.User{
+	ID:   8,
+	Role: model.RoleRecruiter,
+
+	Username: "mockuser_recruiter2",
+	Email:    "",
+	Password: MockPasswordBcrypt, // password
+}
+
+// MockApplicant8 is an example user with role "applicant".
+// The password of this user is changed during the tests.
+var MockApplicant8 = model.User{
+	ID:   9,
+	Role: model.RoleApplicant,
+
+	Username: "mockuser_applicant8",
+	Email:    "mockuser-applicant8@example.com",
+	Password: MockPasswordBcrypt, // password
+}
+
+// MockApplicant9 is an example user with role "applicant".
+// The e-mail address of this user is changed during the tests.
+var MockApplicant9 = model.User{
+	ID:   10,
+	Role: model.RoleApplicant,
+
+	Username: "",
+	Email:    "mockuser-applicant9@example.com",
+	Password: MockPasswordBcrypt, // password
+}
+
+// MockApplicant10 is an example user with role "applicant".
+// This user is locked out after wrong passwords during the tests.
+var MockApplicant10 = model.User{
+	ID:   11,
+	Role: model.RoleApplicant,
+
+	Username: "",
+	Email:    "mockuser-applicant10@example.com",
+	Password: MockPasswordBcrypt, // password
+}
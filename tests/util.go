@@ -4,15 +4,22 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/IV1201-Group-2/login-service/api"
+	"github.com/IV1201-Group-2/login-service/config"
 	"github.com/IV1201-Group-2/login-service/database"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
@@ -24,11 +31,40 @@ import (
 // Database is a single database connection that is maintained for all tests.
 var Database *sql.DB
 
+// Config is the configuration used by all tests.
+// The database URL is set by SetupEnvironment.
+var Config = func() *config.Config {
+	cfg := config.Default()
+	cfg.Database.MaxConnections = 8
+	cfg.Auth.JWTSecret = MockSecret
+	cfg.Auth.Registration = true
+	cfg.Mail.Log = true
+	return cfg
+}()
+
+// UnreachableDriver is the name of a database driver that always fails to connect,
+// like a Postgres server that is down.
+const UnreachableDriver = "unreachable"
+
+type unreachableDriver struct{}
+
+func (unreachableDriver) Open(string) (driver.Conn, error) {
+	return nil, Unreachable(context.Background())
+}
+
+func init() {
+	sql.Register(UnreachableDriver, unreachableDriver{})
+}
+
+// Unreachable returns the error of a connection that was refused.
+// It can be used as the probe of a circuit breaker for a database that is down.
+func Unreachable(context.Context) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+}
+
 // Set up an appropriate environment for testing.
 // If this function succeeds, it returns a cleanup function.
 func SetupEnvironment() (func() error, error) {
-	os.Setenv("DATABASE_MAX_CONNECTIONS", "8")
-	os.Setenv("JWT_SECRET", MockSecret)
 
 	// Set up a Postgres container with our test schema
 	// https://testcontainers.com/guides/getting-started-with-testcontainers-for-go
@@ -50,7 +86,11 @@ func SetupEnvironment() (func() error, error) {
 	if err != nil {
 		return nil, err
 	}
-	Database, err = database.Open(connStr)
+	if err = applyMigrations(connStr); err != nil {
+		return nil, err
+	}
+	Config.Database.URL = connStr
+	Database, err = database.Open(Config.Database)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +107,34 @@ func SetupEnvironment() (func() error, error) {
 	}, nil
 }
 
+// Applies the migrations of the tables owned by the service in order, like the shared database repository does.
+func applyMigrations(connStr string) error {
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("no migrations found")
+	}
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if _, err = db.Exec(string(migration)); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
 // Sends a request to an existing server and returns the response.
 func CustomRequest(t *testing.T, srv *echo.Echo, path string, params map[string]any, headers map[string]string) *http.Response {
 	t.Helper()
@@ -90,12 +158,36 @@ func CustomRequest(t *testing.T, srv *echo.Echo, path string, params map[string]
 func Request(t *testing.T, path string, params map[string]any, headers map[string]string) *http.Response {
 	t.Helper()
 
-	srv, _ := api.NewServer(Database)
+	srv, _, _ := api.NewServer(Database, Config)
 	defer srv.Close()
 
 	return CustomRequest(t, srv, path, params, headers)
 }
 
+// Sends a GET request to an existing server and returns the response.
+func CustomGetRequest(t *testing.T, srv *echo.Echo, path string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+// Sends a GET request to a mock server and returns the response.
+func GetRequest(t *testing.T, path string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	srv, _, _ := api.NewServer(Database, Config)
+	defer srv.Close()
+
+	return CustomGetRequest(t, srv, path, headers)
+}
+
 var letters = []rune("abcdefghijklmnopqrstuvwxyz")
 
 // Generate a random string of a fixed length.